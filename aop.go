@@ -0,0 +1,276 @@
+package wntr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//Around advice for methods of components injected by interface type
+//
+//   Interceptor receives invocation of interface method and
+//   calls inv.Proceed() to invoke next interceptor or the component itself.
+//   Proceed may be called several times (retries) or not called at all.
+//
+//   type TimingInterceptor struct{}
+//
+//   func (*TimingInterceptor) Intercept(inv *wntr.Invocation) []interface{} {
+//           start := time.Now()
+//           defer log.Println(inv.Method, "took", time.Since(start))
+//           return inv.Proceed()
+//   }
+//
+//   By default interceptor is applied to every proxied injection.
+//   Applied injections are narrowed by PointcutAware interface or
+//   by `intercepts:"@tag"` tag of interceptor component, which selects
+//   components declared with given tag. Context fails to start if such
+//   interceptor selects injection of interface without registered proxy.
+//   Interceptors are applied in order, see Ordered
+type Interceptor interface {
+	Intercept(inv *Invocation) []interface{}
+}
+
+//Selects components and interface types intercepted by Interceptor
+type Pointcut func(target Component, iface reflect.Type) bool
+
+//Interceptor that selects intercepted components by itself
+//
+//  Pointcut is evaluated during wiring, so it must not rely
+//  on injected fields of interceptor
+type PointcutAware interface {
+	Pointcut() Pointcut
+}
+
+//Method call passed through interceptor chain
+type Invocation struct {
+	//Intercepted component
+	Target Component
+	//Interface type component is injected by
+	Interface reflect.Type
+	//Name of invoked interface method
+	Method string
+	//Call arguments, variadic arguments are passed as a slice
+	Args []interface{}
+
+	handler *ProxyHandler
+	pos     int
+}
+
+//Creates proxy of interface type that routes calls to ProxyHandler
+//
+//  Factories are generated by `wntrgen -proxy Interface`
+//  and registered in init() of generated file
+type ProxyFactory func(h *ProxyHandler) interface{}
+
+//Invokes interceptor chain on behalf of proxy
+type ProxyHandler struct {
+	target interface{}
+	comp   Component
+	iface  reflect.Type
+	chain  []Interceptor
+}
+
+//InjectionWrapper that proxies components having matching interceptors
+//
+//  Only interfaces with registered ProxyFactory can be intercepted,
+//  see RegisterProxyFactory. Injections selected by pointcut of interceptor
+//  but having no proxy are reported as errors
+type InterceptorProcessor struct {
+	ctx ConfiguredContext
+}
+
+//Component that may substitute value injected into interface typed field
+//
+//   Wrappers are called for `inject:"type"` and every element of
+//   `inject:"all"` fields, after injected component is configured.
+//   Wrappers are applied in order, see Ordered
+type InjectionWrapper interface {
+	WrapInjection(value interface{}, target Component, iface reflect.Type) (interface{}, error)
+}
+
+//Selects components declared with tag
+func TaggedWith(tag string) Pointcut {
+	return func(target Component, iface reflect.Type) bool {
+		_, ok := target.Tags().Lookup(tag)
+		return ok
+	}
+}
+
+//Selects injections by interface type t and components assignable to t
+func OfType(t reflect.Type) Pointcut {
+	return func(target Component, iface reflect.Type) bool {
+		return iface == t || reflect.TypeOf(target.Instance()).AssignableTo(t)
+	}
+}
+
+//Registers proxy factory for interface type iface
+func RegisterProxyFactory(iface reflect.Type, factory ProxyFactory) {
+	gProxyLock.Lock()
+	defer gProxyLock.Unlock()
+	gProxyFactories[iface] = factory
+}
+
+func _() {
+	var _ InjectionWrapper = &InterceptorProcessor{}
+	var _ ContextAware = &InterceptorProcessor{}
+}
+
+/* Implementation */
+
+var gProxyLock sync.RWMutex
+var gProxyFactories = map[reflect.Type]ProxyFactory{}
+
+var gInterceptorType reflect.Type = reflect.TypeOf((*Interceptor)(nil)).Elem()
+var gInjectionWrapperType reflect.Type = reflect.TypeOf((*InjectionWrapper)(nil)).Elem()
+
+func findProxyFactory(iface reflect.Type) ProxyFactory {
+	gProxyLock.RLock()
+	defer gProxyLock.RUnlock()
+	return gProxyFactories[iface]
+}
+
+//Calls next interceptor in chain or the target method
+func (inv *Invocation) Proceed() []interface{} {
+	if inv.pos < len(inv.handler.chain) {
+		next := *inv
+		next.Args = append([]interface{}(nil), inv.Args...)
+		next.pos++
+		return inv.handler.chain[inv.pos].Intercept(&next)
+	}
+	return inv.handler.call(inv.Method, inv.Args)
+}
+
+//Returns last error value of results, if any
+func (inv *Invocation) Error(results []interface{}) error {
+	if len(results) == 0 {
+		return nil
+	}
+	err, _ := results[len(results)-1].(error)
+	return err
+}
+
+//Intercepted component
+func (h *ProxyHandler) Target() Component {
+	return h.comp
+}
+
+//Passes method call through interceptor chain
+func (h *ProxyHandler) Invoke(method string, args ...interface{}) []interface{} {
+	inv := &Invocation{
+		Target:    h.comp,
+		Interface: h.iface,
+		Method:    method,
+		Args:      args,
+		handler:   h,
+	}
+	return inv.Proceed()
+}
+
+//Fails if interceptors returned wrong number of method results
+//
+//  Generated proxies check results before converting them
+func (h *ProxyHandler) CheckResults(method string, out []interface{}, n int) error {
+	if len(out) != n {
+		return fmt.Errorf("Invocation of %v.%v returned %v results, expected %v", h.iface, method, len(out), n)
+	}
+	return nil
+}
+
+func (h *ProxyHandler) call(method string, args []interface{}) []interface{} {
+	m := reflect.ValueOf(h.target).MethodByName(method)
+	if !m.IsValid() {
+		panic(fmt.Sprintf("Method %v not found in %T", method, h.target))
+	}
+
+	mt := m.Type()
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		if a == nil {
+			in[i] = reflect.Zero(mt.In(i))
+		} else {
+			in[i] = reflect.ValueOf(a)
+		}
+	}
+
+	var out []reflect.Value
+	if mt.IsVariadic() {
+		out = m.CallSlice(in)
+	} else {
+		out = m.Call(in)
+	}
+
+	r := make([]interface{}, len(out))
+	for i, v := range out {
+		r[i] = v.Interface()
+	}
+	return r
+}
+
+func (this *InterceptorProcessor) SetContext(c Context) error {
+	if v, ok := c.(*MutableContext); ok {
+		if err := v.FindSingleComponent(&this.ctx); err != nil {
+			return fmt.Errorf("Bad context setup. Failed to FindSingleComponent ConfiguredContext: %v", err)
+		}
+		return nil
+	}
+
+	return errors.New("Unsupported context type")
+}
+
+func (this *InterceptorProcessor) WrapInjection(value interface{}, target Component, iface reflect.Type) (interface{}, error) {
+	//Interceptors are never intercepted themselves
+	if _, ok := target.Instance().(Interceptor); ok {
+		return value, nil
+	}
+
+	chain, selected := this.matchingInterceptors(target, iface)
+	if len(chain) == 0 {
+		return value, nil
+	}
+
+	factory := findProxyFactory(iface)
+	if factory == nil {
+		//Interceptors without pointcut apply to proxied interfaces only
+		if selected {
+			return nil, fmt.Errorf("Interceptors are selecting %v injected as %v, but no proxy is registered for %v", target.Type(), iface, iface)
+		}
+		return value, nil
+	}
+
+	proxy := factory(&ProxyHandler{value, target, iface, chain})
+	if proxy == nil || !reflect.TypeOf(proxy).Implements(iface) {
+		return nil, fmt.Errorf("Proxy factory of %v returned %T", iface, proxy)
+	}
+
+	return proxy, nil
+}
+
+//Returns interceptors applied to injection and whether any of them selects it by pointcut
+func (this *InterceptorProcessor) matchingInterceptors(target Component, iface reflect.Type) ([]Interceptor, bool) {
+	comps := this.ctx.FindComponentsByType(gInterceptorType)
+	sortByOrder(comps)
+
+	r := make([]Interceptor, 0)
+	selected := false
+	for _, ic := range comps {
+		if pc := interceptorPointcut(ic); pc == nil {
+			r = append(r, ic.Instance().(Interceptor))
+		} else if pc(target, iface) {
+			r = append(r, ic.Instance().(Interceptor))
+			selected = true
+		}
+	}
+	return r, selected
+}
+
+//Returns nil for interceptor applied to every proxied injection
+func interceptorPointcut(ic Component) Pointcut {
+	if v, ok := ic.Instance().(PointcutAware); ok {
+		return v.Pointcut()
+	}
+	if tag, ok := ic.Tags().Lookup("intercepts"); ok {
+		return TaggedWith(tag)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package wntr
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Repository interface {
+	Find(id int, fields ...string) (string, error)
+}
+
+//Same as `wntrgen -proxy Repository` would generate
+func init() {
+	RegisterProxyFactory(reflect.TypeOf((*Repository)(nil)).Elem(), func(h *ProxyHandler) interface{} {
+		return &repositoryProxy{h}
+	})
+}
+
+type repositoryProxy struct {
+	h *ProxyHandler
+}
+
+func (p *repositoryProxy) Find(a0 int, a1 ...string) (string, error) {
+	out := p.h.Invoke("Find", a0, a1)
+	if err := p.h.CheckResults("Find", out, 2); err != nil {
+		var z0 string
+		return z0, err
+	}
+	r0, _ := out[0].(string)
+	r1, _ := out[1].(error)
+	return r0, r1
+}
+
+type FlakyRepository struct {
+	failures int
+}
+
+func (r *FlakyRepository) Find(id int, fields ...string) (string, error) {
+	if r.failures > 0 {
+		r.failures--
+		return "", errors.New("connection reset")
+	}
+	return "found", nil
+}
+
+type RetryInterceptor struct {
+	Attempts int
+}
+
+func (i *RetryInterceptor) Intercept(inv *Invocation) []interface{} {
+	for n := 1; ; n++ {
+		out := inv.Proceed()
+		if inv.Error(out) == nil || n == i.Attempts {
+			return out
+		}
+	}
+}
+
+func (i *RetryInterceptor) Pointcut() Pointcut {
+	return OfType(reflect.TypeOf((*Repository)(nil)).Elem())
+}
+
+type TracingInterceptor struct {
+	Calls []string
+}
+
+func (i *TracingInterceptor) Intercept(inv *Invocation) []interface{} {
+	i.Calls = append(i.Calls, inv.Method)
+	return inv.Proceed()
+}
+
+func (i *TracingInterceptor) Order() int {
+	return -1
+}
+
+type RepositoryClient struct {
+	Repo Repository   `inject:"t"`
+	All  []Repository `inject:"all"`
+}
+
+func TestInterceptors(t *testing.T) {
+	var app struct {
+		Proxies InterceptorProcessor
+		Retry   RetryInterceptor
+		Tracing TracingInterceptor `intercepts:"@traced"`
+		Repo    FlakyRepository    `@traced:""`
+		Client  RepositoryClient
+		Ctrl    Controller
+		Dao     DaoImpl2
+	}
+	app.Retry.Attempts = 3
+	app.Repo.failures = 2
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if app.Client.Repo == &app.Repo || app.Client.All[0] == &app.Repo {
+		t.Fatal("Repository was not proxied")
+	}
+
+	if app.Ctrl.Dao != &app.Dao {
+		t.Fatal("Interface without interceptors must not be proxied", app.Ctrl.Dao)
+	}
+
+	r, err := app.Client.Repo.Find(1, "name")
+	if err != nil || r != "found" {
+		t.Fatal("Retries were not applied", r, err)
+	}
+
+	//Tracing is outer interceptor, so retries are not traced
+	if !reflect.DeepEqual(app.Tracing.Calls, []string{"Find"}) {
+		t.Fatal("Bad tracing", app.Tracing.Calls)
+	}
+}
+
+func TestInterceptorOfInterfaceWithoutProxy(t *testing.T) {
+	var app struct {
+		Proxies InterceptorProcessor
+		Tracing TracingInterceptor `intercepts:"@traced"`
+		Dao     DaoImpl2           `@traced:""`
+		Ctrl    Controller
+	}
+
+	if _, err := FastBoot(&app); err == nil {
+		t.Fatal("Interceptor of interface without proxy was not reported")
+	} else {
+		t.Log("Ok:", err)
+	}
+
+	var catchAll struct {
+		Proxies InterceptorProcessor
+		Tracing TracingInterceptor
+		Dao     DaoImpl2
+		Ctrl    Controller
+	}
+
+	ctx, err := FastBoot(&catchAll)
+	if err != nil {
+		t.Fatal("Interceptor without pointcut must skip interfaces without proxy", err)
+	}
+	defer ctx.Stop()
+}
@@ -12,10 +12,18 @@ type AutowiringProcessor struct {
 	//points to component context served by this prcessor
 	ctx        ConfiguredContext
 	configurer ComponentConfigurer
+	//injected components of every autowired component
+	dependencies map[Component][]Component
+}
+
+//Component that knows which components were injected into which
+type DependencyGraph interface {
+	Dependencies(c Component) []Component
 }
 
 func _() {
 	var _ ComponentLifecycle = &AutowiringProcessor{}
+	var _ DependencyGraph = &AutowiringProcessor{}
 }
 
 //Default contructor for AutowiringProcessor
@@ -44,7 +52,7 @@ func (this *AutowiringProcessor) SetContext(c Context) error {
 }
 
 func (this *AutowiringProcessor) OnPrepareComponent(c *ComponentImpl) error {
-	return this.autowireInstance(c.inst)
+	return this.autowireInstance(c)
 }
 
 func (this *AutowiringProcessor) OnComponentReady(c *ComponentImpl) error {
@@ -63,8 +71,15 @@ func (this *AutowiringProcessor) OnDestroyComponent(c *ComponentImpl) error {
 //		- direct interface type
 //
 //  Other cases may cause unpredictable exceptions
-func (this *AutowiringProcessor) autowireInstance(c interface{}) error {
-	t := reflect.ValueOf(c).Elem()
+func (this *AutowiringProcessor) autowireInstance(c *ComponentImpl) error {
+	v := reflect.ValueOf(c.inst)
+
+	//Non-pointer components (maps, funcs) have nothing to autowire
+	if v.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	t := v.Elem()
 
 	//We can autowire just structs
 	if t.Kind() != reflect.Struct {
@@ -76,14 +91,14 @@ func (this *AutowiringProcessor) autowireInstance(c interface{}) error {
 		fld := t.Type().Field(i)
 
 		if v := fld.Tag.Get("inject"); v == "type" || v == "t" {
-			if err := this.injectFieldByType(fldAccessor, fld); err != nil {
+			if err := this.injectFieldByType(fldAccessor, fld, c); err != nil {
 				//return fmt.Errorf("Unable to process field %v, error %v",fld.Name,err)
 				return typeConstructError(t.Type(), fld, err)
 			}
 		}
 
 		if v := fld.Tag.Get("inject"); v == "all" || v == "a" {
-			if err := this.injectAllComponentsByType(fldAccessor, fld); err != nil {
+			if err := this.injectAllComponentsByType(fldAccessor, fld, c); err != nil {
 				//return err
 				return typeConstructError(t.Type(), fld, err)
 			}
@@ -93,11 +108,11 @@ func (this *AutowiringProcessor) autowireInstance(c interface{}) error {
 	return nil
 }
 
-func (this *AutowiringProcessor) injectFieldByType(fld reflect.Value, f reflect.StructField) error {
+func (this *AutowiringProcessor) injectFieldByType(fld reflect.Value, f reflect.StructField, requester *ComponentImpl) error {
 	t := f.Type
 	log.Println("Injecting field", f.Name, "by type")
 
-	candidates := this.ctx.FindComponentsByType(t)
+	candidates := this.findCandidates(t, requester)
 
 	if len(candidates) == 0 {
 		return errors.New(fmt.Sprint("Component not found. Type", t))
@@ -116,12 +131,18 @@ func (this *AutowiringProcessor) injectFieldByType(fld reflect.Value, f reflect.
 		return err
 	}
 
-	fld.Set(reflect.ValueOf(r.Instance()))
+	value, err := this.wrapInjection(r, t)
+	if err != nil {
+		return err
+	}
+
+	fld.Set(reflect.ValueOf(value))
+	this.addDependency(requester, r)
 
 	return nil
 }
 
-func (this *AutowiringProcessor) injectAllComponentsByType(fld reflect.Value, f reflect.StructField) error {
+func (this *AutowiringProcessor) injectAllComponentsByType(fld reflect.Value, f reflect.StructField, requester *ComponentImpl) error {
 	t := f.Type
 	log.Println("Injecting field", f.Name, "by all type instances")
 
@@ -133,7 +154,7 @@ func (this *AutowiringProcessor) injectAllComponentsByType(fld reflect.Value, f
 
 	t = t.Elem() //Get slice's type
 
-	candidates := this.ctx.FindComponentsByType(t)
+	candidates := this.findAllCandidates(t, requester)
 
 	if len(candidates) == 0 {
 		return nil //errors.New(fmt.Sprint("Component not found. Type", t))
@@ -152,7 +173,13 @@ func (this *AutowiringProcessor) injectAllComponentsByType(fld reflect.Value, f
 			return err
 		}
 
-		target.Index(i).Set(reflect.ValueOf(r.Instance()))
+		value, err := this.wrapInjection(r, t)
+		if err != nil {
+			return err
+		}
+
+		target.Index(i).Set(reflect.ValueOf(value))
+		this.addDependency(requester, r)
 	}
 
 	fld.Set(target)
@@ -160,6 +187,54 @@ func (this *AutowiringProcessor) injectAllComponentsByType(fld reflect.Value, f
 	return nil
 }
 
+//Module private components are visible to components of the same module
+func (this *AutowiringProcessor) findCandidates(t reflect.Type, requester *ComponentImpl) []Component {
+	if v, ok := this.ctx.(ScopedConfiguredContext); ok {
+		return v.FindComponentsVisibleTo(t, requester)
+	}
+	return this.ctx.FindComponentsByType(t)
+}
+
+//Components of ancestor contexts are injected too
+func (this *AutowiringProcessor) findAllCandidates(t reflect.Type, requester *ComponentImpl) []Component {
+	if v, ok := this.ctx.(HierarchicalConfiguredContext); ok {
+		return v.FindAllComponentsVisibleTo(t, requester)
+	}
+	return this.findCandidates(t, requester)
+}
+
+//Returns components injected into c
+func (this *AutowiringProcessor) Dependencies(c Component) []Component {
+	return this.dependencies[c]
+}
+
+func (this *AutowiringProcessor) addDependency(c, dep Component) {
+	if this.dependencies == nil {
+		this.dependencies = make(map[Component][]Component)
+	}
+	this.dependencies[c] = append(this.dependencies[c], dep)
+}
+
+//Applies InjectionWrapper components to value injected by interface type
+func (this *AutowiringProcessor) wrapInjection(c Component, t reflect.Type) (interface{}, error) {
+	value := c.Instance()
+	if t.Kind() != reflect.Interface {
+		return value, nil
+	}
+
+	wrappers := this.ctx.FindComponentsByType(gInjectionWrapperType)
+	sortByOrder(wrappers)
+
+	for _, w := range wrappers {
+		var err error
+		if value, err = w.Instance().(InjectionWrapper).WrapInjection(value, c, t); err != nil {
+			return nil, fmt.Errorf("Failed to wrap %v injected as %v: %v", c.Type(), t, err)
+		}
+	}
+
+	return value, nil
+}
+
 func typeConstructError(t reflect.Type, f reflect.StructField, cause error) error {
 	return fmt.Errorf("Unable to costruct type %v:  Failed to fill field %v: %v", t, f, cause)
 }
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const wntrPath = "github.com/d-tar/wntr"
+
+//Tags that cannot be evaluated by generated code
+//
+//  `config` tags are accepted: they are inert without ConfigBinder,
+//  which cannot be declared in generated wiring anyway
+var gUnsupportedTags = []string{"profile", "on-property", "on-bean", "on-missing-bean"}
+
+//Component as FastBoot would register it
+type component struct {
+	name string
+	expr string //Expression evaluating to registered value, like &cfg.Dao
+	path string //Addressable expression of component struct, like cfg.Dao
+	typ  types.Type
+	tags string
+	//Path of declaring module struct. Module names are known at runtime only
+	module string
+}
+
+type generator struct {
+	pkg        *types.Package
+	wntr       *types.Package
+	comps      []*component
+	ctxComp    *component //Pseudo component for StaticContext itself
+	imports    map[string]string
+	states     map[*component]int
+	body       bytes.Buffer
+	lifecycles *types.Interface
+	wrappers   *types.Interface
+	ctxAware   *types.Interface
+	modules    *types.Interface
+}
+
+const (
+	stateNotWired = iota
+	stateResolving
+	stateResolved
+)
+
+//Generates wiring source for configuration struct typeName of package in dir
+//
+//  File named skipFile is not parsed, so stale generated code does not break generation
+func Generate(dir, typeName, funcName, skipFile string) ([]byte, error) {
+	pkg, err := loadPackage(dir, skipFile)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := pkg.Scope().Lookup(typeName)
+	if obj == nil {
+		return nil, fmt.Errorf("Type %v not found in package %v", typeName, pkg.Name())
+	}
+
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("Type %v is not a struct", typeName)
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		imports: make(map[string]string),
+		states:  make(map[*component]int),
+	}
+	g.wntr = findPackage(pkg, wntrPath, make(map[*types.Package]bool))
+	if g.wntr == nil {
+		return nil, fmt.Errorf("Package %v does not depend on %v", pkg.Name(), wntrPath)
+	}
+
+	g.setupBuiltins()
+
+	root := &component{expr: "cfg", path: "cfg", typ: types.NewPointer(obj.Type())}
+	root.module = g.moduleOf(root, "")
+	g.comps = append(g.comps, root)
+
+	if err := g.collect("cfg", st, root.module); err != nil {
+		return nil, err
+	}
+
+	for _, c := range g.comps {
+		if err := g.configure(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.render(typeName, funcName)
+}
+
+func loadPackage(dir, skipFile string) (*types.Package, error) {
+	fset := token.NewFileSet()
+
+	filter := func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != skipFile
+	}
+
+	pkgs, err := parser.ParseDir(fset, dir, filter, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("Expected single package in %v, found %v", dir, len(pkgs))
+	}
+
+	var files []*ast.File
+	var name string
+	for n, p := range pkgs {
+		name = n
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+	}
+
+	path := name
+	if bp, err := build.ImportDir(dir, 0); err == nil && bp.ImportPath != "" && bp.ImportPath != "." {
+		path = bp.ImportPath
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(path, fset, files, nil)
+}
+
+func findPackage(pkg *types.Package, path string, seen map[*types.Package]bool) *types.Package {
+	if pkg.Path() == path {
+		return pkg
+	}
+	seen[pkg] = true
+	for _, p := range pkg.Imports() {
+		if seen[p] {
+			continue
+		}
+		if r := findPackage(p, path, seen); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+func (g *generator) setupBuiltins() {
+	if obj := g.wntr.Scope().Lookup("StaticContext"); obj != nil {
+		g.ctxComp = &component{name: "StaticContext", expr: "ctx", typ: types.NewPointer(obj.Type())}
+		g.states[g.ctxComp] = stateResolved
+	}
+
+	if obj := g.wntr.Scope().Lookup("ComponentLifecycle"); obj != nil {
+		g.lifecycles = obj.Type().Underlying().(*types.Interface)
+	}
+
+	if obj := g.wntr.Scope().Lookup("InjectionWrapper"); obj != nil {
+		g.wrappers = obj.Type().Underlying().(*types.Interface)
+	}
+
+	if obj := g.wntr.Scope().Lookup("ContextAware"); obj != nil {
+		g.ctxAware = obj.Type().Underlying().(*types.Interface)
+	}
+
+	if obj := g.wntr.Scope().Lookup("Module"); obj != nil {
+		g.modules = obj.Type().Underlying().(*types.Interface)
+	}
+}
+
+//Mirrors declaredModule, promoted ModuleName does not make a module
+func (g *generator) moduleOf(c *component, parent string) string {
+	if g.modules == nil || !types.Implements(c.typ, g.modules) {
+		return parent
+	}
+
+	sel := types.NewMethodSet(c.typ).Lookup(g.wntr, "ModuleName")
+	if sel != nil && len(sel.Index()) > 1 {
+		return parent
+	}
+	return c.path
+}
+
+//Mirrors populateModule
+func (g *generator) collect(path string, st *types.Struct, module string) error {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag := st.Tag(i)
+		fpath := path + "." + f.Name()
+
+		for _, t := range gUnsupportedTags {
+			if _, ok := reflect.StructTag(tag).Lookup(t); ok {
+				return fmt.Errorf("%v: tag '%v' is not supported by generated wiring", fpath, t)
+			}
+		}
+
+		name := f.Name()
+		if v := reflect.StructTag(tag).Get("name"); v != "" {
+			name = v
+		}
+
+		switch u := f.Type().Underlying().(type) {
+		case *types.Interface:
+			//Concrete type is known at runtime only, so it cannot be autowired
+			return fmt.Errorf("%v: interface components are not supported by generated wiring", fpath)
+		case *types.Struct, *types.Signature:
+			if !f.Exported() && f.Pkg() != g.pkg {
+				return fmt.Errorf("%v: unexported field of other package cannot be registered", fpath)
+			}
+
+			c := &component{name: name, expr: "&" + fpath, path: fpath, typ: types.NewPointer(f.Type()), tags: tag, module: module}
+
+			if g.lifecycles != nil && types.Implements(c.typ, g.lifecycles) {
+				return fmt.Errorf("%v: lifecycle processors are not supported by generated wiring", fpath)
+			}
+
+			if g.wrappers != nil && types.Implements(c.typ, g.wrappers) {
+				return fmt.Errorf("%v: injection wrappers are not supported by generated wiring", fpath)
+			}
+
+			//StaticContext is not a Context, SetContext cannot be called
+			if g.ctxAware != nil && types.Implements(c.typ, g.ctxAware) {
+				return fmt.Errorf("%v: context aware components are not supported by generated wiring", fpath)
+			}
+
+			s, isStruct := u.(*types.Struct)
+			nested := g.moduleOf(c, module)
+
+			//Embedded structs and nested modules declare own components
+			if isStruct && (f.Anonymous() || nested != module) {
+				if f.Anonymous() {
+					c.name = ""
+				}
+				c.tags, c.module = "", nested
+				g.comps = append(g.comps, c)
+				if err := g.collect(fpath, s, nested); err != nil {
+					return err
+				}
+				continue
+			}
+
+			g.comps = append(g.comps, c)
+		}
+	}
+	return nil
+}
+
+//Mirrors MutableContext.FindComponentsVisibleTo
+func (g *generator) candidates(t types.Type, requester *component) []*component {
+	r := make([]*component, 0)
+	for _, c := range g.comps {
+		private := reflect.StructTag(c.tags).Get("module") == "private"
+		if types.AssignableTo(c.typ, t) && (!private || c.module == requester.module) {
+			r = append(r, c)
+		}
+	}
+	if len(r) == 0 && g.ctxComp != nil && types.AssignableTo(g.ctxComp.typ, t) {
+		r = append(r, g.ctxComp)
+	}
+	return r
+}
+
+//Mirrors StandardLifecycle.ConfigureComponent with
+//TwoPhaseInitializer and AutowiringProcessor registered
+func (g *generator) configure(c *component) error {
+	switch g.states[c] {
+	case stateResolving:
+		return fmt.Errorf("Circular dependency on %v", c.expr)
+	case stateResolved:
+		return nil
+	}
+	g.states[c] = stateResolving
+
+	g.emitStep("Prepare", c.expr)
+
+	if err := g.autowire(c); err != nil {
+		return err
+	}
+
+	g.emitStep("Ready", c.expr)
+
+	g.states[c] = stateResolved
+	return nil
+}
+
+func (g *generator) emitStep(step, expr string) {
+	fmt.Fprintf(&g.body, "if err := ctx.%v(%v); err != nil {\nreturn nil, ctx.Abort(err)\n}\n", step, expr)
+}
+
+//Mirrors AutowiringProcessor.autowireInstance
+func (g *generator) autowire(c *component) error {
+	ptr, ok := c.typ.(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	st, ok := ptr.Elem().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		mode := reflect.StructTag(st.Tag(i)).Get("inject")
+		target := c.path + "." + f.Name()
+
+		if mode == "" {
+			continue
+		}
+
+		if !f.Exported() {
+			return fmt.Errorf("%v: field cannot be set. Is it declared public?", target)
+		}
+
+		switch mode {
+		case "type", "t":
+			deps := g.candidates(f.Type(), c)
+			if len(deps) == 0 {
+				return fmt.Errorf("%v: component not found. Type %v", target, f.Type())
+			}
+			if len(deps) > 1 {
+				return fmt.Errorf("%v: too many components with type %v. Expected 1, Got: %v", target, f.Type(), len(deps))
+			}
+			if err := g.configure(deps[0]); err != nil {
+				return fmt.Errorf("%v: %v", target, err)
+			}
+			fmt.Fprintf(&g.body, "%v = %v\n", target, deps[0].expr)
+
+		case "all", "a":
+			sl, ok := f.Type().Underlying().(*types.Slice)
+			if !ok {
+				return fmt.Errorf("%v: bad inject:all field. Slice expected, got: %v", target, f.Type())
+			}
+			deps := g.candidates(sl.Elem(), c)
+			if len(deps) == 0 {
+				continue
+			}
+			exprs := make([]string, len(deps))
+			for i, d := range deps {
+				if err := g.configure(d); err != nil {
+					return fmt.Errorf("%v: %v", target, err)
+				}
+				exprs[i] = d.expr
+			}
+			fmt.Fprintf(&g.body, "%v = %v{%v}\n", target, types.TypeString(f.Type(), g.qualifier), strings.Join(exprs, ", "))
+
+		default:
+			return fmt.Errorf("%v: unknown inject mode '%v'", target, mode)
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg {
+		return ""
+	}
+	g.imports[p.Path()] = p.Name()
+	return p.Name()
+}
+
+func (g *generator) render(typeName, funcName string) ([]byte, error) {
+	var b bytes.Buffer
+
+	wntrName := g.qualifier(g.wntr)
+	if wntrName != "" {
+		wntrName += "."
+	}
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "ctx := %vNewStaticContext()\n\n", wntrName)
+
+	for _, c := range g.comps {
+		fmt.Fprintf(&head, "ctx.Register(%q, %v, %v)\n", c.name, c.expr, quoteTags(c.tags))
+	}
+
+	fmt.Fprintf(&b, "// Code generated by wntrgen -type %v; DO NOT EDIT.\n\n", typeName)
+	fmt.Fprintf(&b, "package %v\n\n", g.pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	if len(paths) > 0 {
+		fmt.Fprintln(&b, "import (")
+		for _, p := range paths {
+			fmt.Fprintf(&b, "%q\n", p)
+		}
+		fmt.Fprintln(&b, ")")
+	}
+
+	fmt.Fprintf(&b, "\n//Creates and initializes %v components without reflection\n", typeName)
+	fmt.Fprintf(&b, "func %v(cfg *%v) (*%vStaticContext, error) {\n", funcName, typeName, wntrName)
+	b.Write(head.Bytes())
+	fmt.Fprintln(&b)
+	b.Write(g.body.Bytes())
+	fmt.Fprintln(&b, "if err := ctx.StartModules(); err != nil {\nreturn nil, ctx.Abort(err)\n}")
+	fmt.Fprintln(&b, "\nreturn ctx, nil\n}")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Generated bad code: %v\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+//Tags are rendered as raw strings when possible
+func quoteTags(tags string) string {
+	if strings.Contains(tags, "`") {
+		return strconv.Quote(tags)
+	}
+	return "`" + tags + "`"
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateWiring(t *testing.T) {
+	src, err := Generate("testdata/app", "App", "BuildAppContext", "app_wntr.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := string(src)
+	t.Log(code)
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "app_wntr.go", src, 0); err != nil {
+		t.Fatal("Generated code is not valid", err)
+	}
+
+	//Dependencies are configured before they are injected,
+	//dependent component is ready after injection
+	order := []string{
+		"ctx.Register(\"Service\", &cfg.Service, `@role:\"service\"`)",
+		"ctx.Prepare(&cfg.Service)",
+		"ctx.Prepare(&cfg.Dao)",
+		"cfg.Dao.Log = &cfg.Base.Log",
+		"ctx.Ready(&cfg.Dao)",
+		"cfg.Service.Dao = &cfg.Dao",
+		"cfg.Service.All = []Dao{&cfg.Dao}",
+		"cfg.Service.Ctx = ctx",
+		"ctx.Ready(&cfg.Service)",
+	}
+
+	pos := 0
+	for _, line := range order {
+		i := strings.Index(code[pos:], line)
+		if i < 0 {
+			t.Fatal("Expected line not found in order:", line)
+		}
+		pos += i + len(line)
+	}
+}
+
+//Compiles generated wiring and proxies with the fixture and runs its test
+func TestGeneratedWiringRuns(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	src, err := Generate("testdata/app", "App", "BuildAppContext", "app_wntr.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Temporary module imports wntr from this source tree
+	out, err := exec.Command(gobin, "list", "-m", "-f", "{{.Path}} {{.Dir}} {{.GoVersion}}").Output()
+	mod := strings.Fields(string(out))
+	if err != nil || len(mod) != 3 {
+		t.Skip("wntr module is not available", err)
+	}
+
+	dir := t.TempDir()
+	gomod := fmt.Sprintf("module wntrgenrun\n\ngo %v\n\nrequire %v v0.0.0\n\nreplace %v => %v\n", mod[2], mod[0], mod[0], mod[1])
+
+	proxies, err := GenerateProxies("testdata/app", []string{"Dao", "Store"}, "wntr_proxy.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{"app_wntr.go": src, "wntr_proxy.go": proxies, "go.mod": []byte(gomod)}
+	if sum, err := os.ReadFile(filepath.Join(mod[1], "go.sum")); err == nil {
+		files["go.sum"] = sum
+	}
+	for _, name := range []string{"app.go", "app_wntr_test.go"} {
+		if files[name], err = os.ReadFile(filepath.Join("testdata/app", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(gobin, "test", "-mod=mod", "-count=1", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Generated code failed: %v\n%s", err, out)
+	}
+}
+
+func TestGenerateRejectsInterfaceComponents(t *testing.T) {
+	_, err := Generate("testdata/app", "WithInterface", "BuildWithInterfaceContext", "")
+	if err == nil || !strings.Contains(err.Error(), "interface components are not supported") {
+		t.Fatal("Interface component was not rejected", err)
+	}
+}
+
+func TestGenerateRejectsContextAwareComponents(t *testing.T) {
+	_, err := Generate("testdata/app", "WithContextAware", "BuildWithContextAwareContext", "")
+	if err == nil || !strings.Contains(err.Error(), "context aware components are not supported") {
+		t.Fatal("Context aware component was not rejected", err)
+	}
+}
+
+func TestGenerateReportsCycles(t *testing.T) {
+	_, err := Generate("testdata/app", "Cyclic", "BuildCyclicContext", "")
+	if err == nil || !strings.Contains(err.Error(), "Circular dependency") {
+		t.Fatal("Circular dependency was not detected", err)
+	}
+}
+
+func TestGenerateProxies(t *testing.T) {
+	src, err := GenerateProxies("testdata/app", []string{"Dao", "Store"}, "wntr_proxy.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := string(src)
+	t.Log(code)
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "wntr_proxy.go", src, 0); err != nil {
+		t.Fatal("Generated code is not valid", err)
+	}
+
+	expected := []string{
+		"wntr.RegisterProxyFactory(reflect.TypeOf((*Store)(nil)).Elem(), func(h *wntr.ProxyHandler) interface{} {",
+		"func (p *storeProxy) Find() string {",
+		"func (p *storeProxy) Put(a0 string, a1 ...string) error {",
+		"out := p.h.Invoke(\"Put\", a0, a1)",
+		"if err := p.h.CheckResults(\"Put\", out, 1); err != nil {\n\t\treturn err\n\t}",
+		"r0, _ := out[0].(error)",
+		"var z1 bool\n\t\treturn z0, z1, err",
+		"if err := p.h.CheckResults(\"Find\", out, 1); err != nil {\n\t\tpanic(err)\n\t}",
+		"func (p *storeProxy) Get(a0 string) (string, bool, error) {",
+		"return r0, r1, r2",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(code, line) {
+			t.Fatal("Expected line not found:", line)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+//Command wntrgen generates reflection free wiring code for
+//declarative wntr configurations
+//
+//   //go:generate wntrgen -type App
+//
+//   var app App
+//   ctx, err := BuildAppContext(&app)
+//   ...
+//   ctx.Stop()
+//
+//  Generated function registers every component FastBoot would register,
+//  assigns `inject` fields and calls PreInit/PostInit in the same order
+//  as StandardLifecycle does. Wiring errors are reported by generator,
+//  type errors are reported by compiler.
+//
+//  Conditional tags, interface components, custom ComponentLifecycle processors,
+//  injection wrappers (interceptors) and ContextAware components (HealthService,
+//  ConfigFiles, AdminService) are not supported in generated wiring.
+//  `config` tags are ignored, configured fields have to be set by caller
+//
+//  With -proxy flag wntrgen generates interceptable proxies for
+//  listed interfaces instead, see wntr.Interceptor
+//
+//   //go:generate wntrgen -proxy Dao,Cache
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeName = flag.String("type", "", "name of declarative configuration struct; -type or -proxy must be set")
+	proxies  = flag.String("proxy", "", "comma-separated list of interfaces to generate proxies for")
+	funcName = flag.String("func", "", "name of generated function; default Build<type>Context")
+	output   = flag.String("output", "", "output file name; default <type>_wntr.go or wntr_proxy.go")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("wntrgen: ")
+
+	flag.Parse()
+
+	if (*typeName == "") == (*proxies == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if *proxies != "" {
+		if *output == "" {
+			*output = "wntr_proxy.go"
+		}
+		outPath := filepath.Join(dir, *output)
+
+		src, err := GenerateProxies(dir, strings.Split(*proxies, ","), filepath.Base(outPath))
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeOutput(outPath, src)
+		return
+	}
+
+	if *funcName == "" {
+		*funcName = "Build" + *typeName + "Context"
+	}
+
+	if *output == "" {
+		*output = strings.ToLower(*typeName) + "_wntr.go"
+	}
+	outPath := filepath.Join(dir, *output)
+
+	src, err := Generate(dir, *typeName, *funcName, filepath.Base(outPath))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	writeOutput(outPath, src)
+}
+
+func writeOutput(outPath string, src []byte) {
+	if err := ioutil.WriteFile(outPath, src, 0644); err != nil {
+		log.Fatal(fmt.Errorf("Failed to write %v: %v", outPath, err))
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+type proxyGenerator struct {
+	pkg     *types.Package
+	imports map[string]string
+	body    bytes.Buffer
+}
+
+//Generates interceptable proxies for interfaces of package in dir
+//
+//  Proxies route every method call through wntr.ProxyHandler
+//  and are registered by init() of generated file.
+//  File named skipFile is not parsed
+func GenerateProxies(dir string, names []string, skipFile string) ([]byte, error) {
+	pkg, err := loadPackage(dir, skipFile)
+	if err != nil {
+		return nil, err
+	}
+
+	wntr := findPackage(pkg, wntrPath, make(map[*types.Package]bool))
+	if wntr == nil {
+		return nil, fmt.Errorf("Package %v does not depend on %v", pkg.Name(), wntrPath)
+	}
+
+	g := &proxyGenerator{
+		pkg:     pkg,
+		imports: map[string]string{"reflect": "reflect"},
+	}
+	wntrName := g.qualifier(wntr)
+	if wntrName != "" {
+		wntrName += "."
+	}
+
+	var init bytes.Buffer
+	for _, name := range names {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return nil, fmt.Errorf("Type %v not found in package %v", name, pkg.Name())
+		}
+
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		if !ok {
+			return nil, fmt.Errorf("Type %v is not an interface", name)
+		}
+
+		proxy := proxyTypeName(name)
+		fmt.Fprintf(&init, "%vRegisterProxyFactory(reflect.TypeOf((*%v)(nil)).Elem(), func(h *%vProxyHandler) interface{} {\nreturn &%v{h}\n})\n",
+			wntrName, name, wntrName, proxy)
+
+		fmt.Fprintf(&g.body, "\n//Interceptable proxy of %v\ntype %v struct {\nh *%vProxyHandler\n}\n", name, proxy, wntrName)
+		for i := 0; i < iface.NumMethods(); i++ {
+			g.method(proxy, iface.Method(i))
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by wntrgen -proxy %v; DO NOT EDIT.\n\n", strings.Join(names, ","))
+	fmt.Fprintf(&b, "package %v\n\n", pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintln(&b, "import (")
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%q\n", p)
+	}
+	fmt.Fprintln(&b, ")")
+
+	fmt.Fprintf(&b, "\nfunc init() {\n%s}\n", init.Bytes())
+	b.Write(g.body.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Generated bad code: %v\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+func proxyTypeName(iface string) string {
+	return strings.ToLower(iface[:1]) + iface[1:] + "Proxy"
+}
+
+func (g *proxyGenerator) qualifier(p *types.Package) string {
+	if p == g.pkg {
+		return ""
+	}
+	g.imports[p.Path()] = p.Name()
+	return p.Name()
+}
+
+func (g *proxyGenerator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+func (g *proxyGenerator) method(proxy string, m *types.Func) {
+	sig := m.Type().(*types.Signature)
+
+	params := make([]string, 0)
+	args := []string{fmt.Sprintf("%q", m.Name())}
+	for i := 0; i < sig.Params().Len(); i++ {
+		name := fmt.Sprintf("a%v", i)
+		t := g.typeString(sig.Params().At(i).Type())
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			t = "..." + strings.TrimPrefix(t, "[]")
+		}
+		params = append(params, name+" "+t)
+		args = append(args, name)
+	}
+
+	results := make([]string, 0)
+	for i := 0; i < sig.Results().Len(); i++ {
+		results = append(results, g.typeString(sig.Results().At(i).Type()))
+	}
+
+	ret := strings.Join(results, ", ")
+	if len(results) > 1 {
+		ret = "(" + ret + ")"
+	}
+
+	fmt.Fprintf(&g.body, "\nfunc (p *%v) %v(%v) %v {\n", proxy, m.Name(), strings.Join(params, ", "), ret)
+
+	call := fmt.Sprintf("p.h.Invoke(%v)", strings.Join(args, ", "))
+	if len(results) == 0 {
+		fmt.Fprintf(&g.body, "%v\n}\n", call)
+		return
+	}
+
+	fmt.Fprintf(&g.body, "out := %v\n", call)
+	fmt.Fprintf(&g.body, "if err := p.h.CheckResults(%q, out, %v); err != nil {\n", m.Name(), len(results))
+	last := sig.Results().At(len(results) - 1).Type()
+	if types.Identical(last, types.Universe.Lookup("error").Type()) {
+		zeros := make([]string, 0)
+		for i, t := range results[:len(results)-1] {
+			fmt.Fprintf(&g.body, "var z%v %v\n", i, t)
+			zeros = append(zeros, fmt.Sprintf("z%v", i))
+		}
+		fmt.Fprintf(&g.body, "return %v\n}\n", strings.Join(append(zeros, "err"), ", "))
+	} else {
+		fmt.Fprintln(&g.body, "panic(err)\n}")
+	}
+
+	names := make([]string, len(results))
+	for i, t := range results {
+		names[i] = fmt.Sprintf("r%v", i)
+		fmt.Fprintf(&g.body, "%v, _ := out[%v].(%v)\n", names[i], i, t)
+	}
+	fmt.Fprintf(&g.body, "return %v\n}\n", strings.Join(names, ", "))
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"github.com/d-tar/wntr"
+)
+
+type Dao interface {
+	Find() string
+}
+
+type RealDao struct {
+	Log *Log `inject:"t"`
+}
+
+func (d *RealDao) Find() string {
+	return "real"
+}
+
+func (d *RealDao) PostInit() error {
+	d.Log.Add("dao.PostInit")
+	return nil
+}
+
+type Log struct {
+	Lines []string
+}
+
+func (l *Log) Add(line string) {
+	l.Lines = append(l.Lines, line)
+}
+
+type Service struct {
+	Dao  Dao                    `inject:"t"`
+	All  []Dao                  `inject:"all"`
+	Ctx  wntr.ConfiguredContext `inject:"t"`
+	Log  *Log                   `inject:"t"`
+	name string
+}
+
+func (s *Service) PostInit() error {
+	s.Log.Add("service.PostInit")
+	return nil
+}
+
+type Base struct {
+	Log Log
+}
+
+type App struct {
+	Base
+	Service Service `@role:"service"`
+	Dao     RealDao
+}
+
+type NodeA struct {
+	B *NodeB `inject:"t"`
+}
+
+type NodeB struct {
+	A *NodeA `inject:"t"`
+}
+
+type Cyclic struct {
+	A NodeA
+	B NodeB
+}
+
+type Store interface {
+	Dao
+	Put(key string, values ...string) error
+	Get(key string) (string, bool, error)
+}
+
+type WithInterface struct {
+	Dao Dao
+}
+
+type Aware struct {
+	ctx wntr.Context
+}
+
+func (a *Aware) SetContext(c wntr.Context) error {
+	a.ctx = c
+	return nil
+}
+
+type WithContextAware struct {
+	Aware Aware
+}
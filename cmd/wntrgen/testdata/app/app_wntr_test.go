@@ -0,0 +1,31 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+//Runs against app_wntr.go generated by TestGeneratedWiringRuns
+func TestBuildAppContext(t *testing.T) {
+	var app App
+	ctx, err := BuildAppContext(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.Service.Dao != &app.Dao || len(app.Service.All) != 1 || app.Service.All[0] != &app.Dao {
+		t.Fatal("Dao was not injected", app.Service)
+	}
+
+	if app.Service.Ctx == nil || app.Service.Log != &app.Base.Log || app.Dao.Log != &app.Base.Log {
+		t.Fatal("Context or log was not injected", app.Service)
+	}
+
+	if !reflect.DeepEqual(app.Base.Log.Lines, []string{"dao.PostInit", "service.PostInit"}) {
+		t.Fatal("Bad initialization order", app.Base.Log.Lines)
+	}
+
+	if err := ctx.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
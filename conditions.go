@@ -0,0 +1,228 @@
+package wntr
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+)
+
+//Holder for active profiles of context
+//
+//   Register it to override WNTR_PROFILES environment variable
+//
+//   var app struct {
+//           Profiles wntr.ActiveProfiles
+//   }
+//
+//   app.Profiles.Names = []string{"dev"}
+type ActiveProfiles struct {
+	Names []string
+}
+
+//Tags that make component registration conditional
+//
+//   profile:"dev,test"          - any of profiles is active, "!prod" negates
+//   on-property:"cache.enabled" - property is set in any PropertySource,
+//                                 "cache.kind=redis" compares value too
+//   on-bean:"*app.RealDao"      - component of given type is registered
+//   on-missing-bean:"app.Dao"   - no component of given type is registered,
+//                                 interface names match implementations
+//
+//  Type names are resolved among types of declared components and their
+//  fields, e.g. app.Dao is known when some component injects it.
+//  Names that resolve to no type fail context start.
+//
+//  Conditional components are registered on context start, before
+//  any component is configured, in order of declaration
+var gConditionTags = []string{"profile", "on-property", "on-bean", "on-missing-bean"}
+
+var gActiveProfilesType reflect.Type = reflect.TypeOf((*ActiveProfiles)(nil))
+
+/* Implementation */
+
+type conditionalComponent struct {
+	inst   interface{}
+	tags   string
+	name   string
+	module string
+}
+
+func hasConditions(tags string) bool {
+	st := reflect.StructTag(tags)
+	for _, name := range gConditionTags {
+		if _, ok := st.Lookup(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+//Registers every conditional component which conditions are met
+func (c *MutableContext) registerConditionalComponents() error {
+	pending := c.conditional
+	c.conditional = nil
+
+	known := knownTypes(c.components, pending)
+
+	for _, cc := range pending {
+		ok, err := c.checkConditions(cc, c.components, known, true)
+		if err != nil {
+			return fmt.Errorf("Unable to check conditions of %T: %v", cc.inst, err)
+		}
+
+		if !ok {
+			log.Println("Skipping component", reflect.TypeOf(cc.inst), "conditions not met", cc.tags)
+			continue
+		}
+
+		c.registerComponent(cc.inst, cc.tags, cc.name, cc.module)
+	}
+
+	return nil
+}
+
+//Checks conditions of component against already registered components.
+//Property sources are loaded if load is set, otherwise they are only read
+func (c *MutableContext) checkConditions(cc *conditionalComponent, comps []*ComponentImpl, known map[string]reflect.Type, load bool) (bool, error) {
+	tags := reflect.StructTag(cc.tags)
+
+	if v, ok := tags.Lookup("profile"); ok && !c.matchProfiles(v) {
+		return false, nil
+	}
+
+	if v, ok := tags.Lookup("on-property"); ok {
+		set, err := c.propertyCondition(v, load)
+		if err != nil || !set {
+			return false, err
+		}
+	}
+
+	for _, cond := range []string{"on-bean", "on-missing-bean"} {
+		v, ok := tags.Lookup(cond)
+		if !ok {
+			continue
+		}
+
+		t, found := known[v]
+		if !found {
+			return false, fmt.Errorf("Unknown type '%v' of %v condition", v, cond)
+		}
+		if hasComponentOfType(comps, t) != (cond == "on-bean") {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+//Returns profiles of context
+func (c *MutableContext) ActiveProfiles() []string {
+	comps := c.FindComponentsByType(gActiveProfilesType)
+
+	if len(comps) > 0 {
+		return comps[len(comps)-1].Instance().(*ActiveProfiles).Names
+	}
+
+	return profilesFromEnv()
+}
+
+func (c *MutableContext) matchProfiles(expr string) bool {
+	active := make(map[string]bool)
+	for _, p := range c.ActiveProfiles() {
+		active[p] = true
+	}
+
+	for _, p := range strings.Split(expr, ",") {
+		p = strings.TrimSpace(p)
+
+		if strings.HasPrefix(p, "!") {
+			if !active[p[1:]] {
+				return true
+			}
+		} else if active[p] {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Source that can read its properties without keeping them
+type propertyPeeker interface {
+	peekProperties() (PropertySource, error)
+}
+
+func (c *MutableContext) propertyCondition(expr string, load bool) (bool, error) {
+	name, expected := expr, ""
+	compare := false
+
+	if pos := strings.Index(expr, "="); pos >= 0 {
+		name, expected, compare = expr[:pos], expr[pos+1:], true
+	}
+
+	for _, comp := range c.FindComponentsByType(gPropertySourceType) {
+		src := comp.Instance().(PropertySource)
+
+		//Property sources have to be loaded before asking them
+		if v, ok := src.(LoadablePropertySource); ok && load {
+			if err := v.LoadProperties(); err != nil {
+				return false, err
+			}
+		} else if v, ok := src.(propertyPeeker); ok && !load {
+			peeked, err := v.peekProperties()
+			if err != nil {
+				return false, err
+			}
+			src = peeked
+		}
+
+		v, ok := src.Property(name)
+		if ok && (!compare || fmt.Sprint(v) == expected) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+//Component of struct type matches by pointer, interface matches implementations
+func hasComponentOfType(comps []*ComponentImpl, t reflect.Type) bool {
+	for _, comp := range comps {
+		if comp.ty.AssignableTo(t) || (comp.ty.Kind() == reflect.Ptr && comp.ty.Elem() == t) {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns types of components, conditional ones too, and types they refer to by name
+func knownTypes(comps []*ComponentImpl, conditional []*conditionalComponent) map[string]reflect.Type {
+	known := make(map[string]reflect.Type)
+	for _, comp := range comps {
+		collectTypes(comp.ty, known)
+	}
+	for _, cc := range conditional {
+		collectTypes(reflect.TypeOf(cc.inst), known)
+	}
+	return known
+}
+
+func collectTypes(t reflect.Type, known map[string]reflect.Type) {
+	if _, ok := known[t.String()]; ok {
+		return
+	}
+	known[t.String()] = t
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Chan:
+		collectTypes(t.Elem(), known)
+	case reflect.Map:
+		collectTypes(t.Key(), known)
+		collectTypes(t.Elem(), known)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			collectTypes(t.Field(i).Type, known)
+		}
+	}
+}
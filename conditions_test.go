@@ -0,0 +1,113 @@
+package wntr
+
+import (
+	"testing"
+)
+
+type RealDao struct {
+}
+
+func (d *RealDao) DoJob() {
+}
+
+type FakeDao struct {
+}
+
+func (d *FakeDao) DoJob() {
+}
+
+type ProfiledApp struct {
+	Profiles ActiveProfiles
+	Real     RealDao `profile:"!dev"`
+	Fake     FakeDao `profile:"dev"`
+	Ctrl     Controller
+}
+
+func TestProfileConditions(t *testing.T) {
+	for _, profile := range []string{"dev", "prod"} {
+		var app ProfiledApp
+		app.Profiles.Names = []string{profile}
+
+		ctx, err := FastBoot(&app)
+		if err != nil {
+			t.Fatal(profile, err)
+		}
+
+		_, isFake := app.Ctrl.Dao.(*FakeDao)
+		if isFake != (profile == "dev") {
+			t.Fatal("Bad dao injected for profile", profile, app.Ctrl.Dao)
+		}
+
+		ctx.Stop()
+	}
+}
+
+func TestBeanConditions(t *testing.T) {
+	var app struct {
+		Real RealDao
+		Fake FakeDao `on-missing-bean:"wntr.RealDao"`
+		Ctrl Controller
+		Wrap AllDaoStruct `on-bean:"*wntr.RealDao"`
+	}
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if app.Ctrl.Dao != &app.Real {
+		t.Fatal("Fake dao was registered", app.Ctrl.Dao)
+	}
+
+	if len(app.Wrap.Dao) != 1 {
+		t.Fatal("Component with on-bean condition was not configured", app.Wrap)
+	}
+}
+
+func TestInterfaceBeanConditions(t *testing.T) {
+	var app struct {
+		Real RealDao
+		Fake FakeDao `on-missing-bean:"wntr.Dao2"`
+		Ctrl Controller
+	}
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if app.Ctrl.Dao != &app.Real {
+		t.Fatal("Fake dao was registered next to implementation of interface", app.Ctrl.Dao)
+	}
+
+	var unknown struct {
+		Fake FakeDao `on-bean:"wntr.NoSuchDao"`
+	}
+	if ctx, err := FastBoot(&unknown); err == nil {
+		ctx.Stop()
+		t.Fatal("Unknown type name was accepted")
+	}
+}
+
+func TestPropertyConditions(t *testing.T) {
+	var app struct {
+		Props PropertySource
+		Real  RealDao `on-property:"dao.kind=real"`
+		Fake  FakeDao `on-property:"dao.fake"`
+		Ctrl  Controller
+	}
+
+	app.Props = MapPropertySource{"dao": map[string]interface{}{"kind": "real"}}
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if app.Ctrl.Dao != &app.Real {
+		t.Fatal("Bad dao injected", app.Ctrl.Dao)
+	}
+}
@@ -0,0 +1,314 @@
+package wntr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Default component that enables `config` tag on components
+//
+//   Component declared as
+//
+//	Db DatabaseConfig `config:"database"`
+//
+//   is filled with "database" section merged from every PropertySource
+//   in context. Fields are matched by `config:"name"` tag or by name,
+//   ignoring case, '-' and '_'. Fields marked `config:"name,required"`
+//   must be present in configuration.
+//
+//   Binding is done before PreInit phase, so components may
+//   validate bound values in PreInit
+type ConfigBinder struct {
+	ctx        ConfiguredContext
+	configurer ComponentConfigurer
+}
+
+func _() {
+	var _ ComponentLifecycle = &ConfigBinder{}
+	var _ Ordered = &ConfigBinder{}
+}
+
+/* implementation */
+
+func (this *ConfigBinder) SetContext(c Context) error {
+	if v, ok := c.(*MutableContext); ok {
+		if err := v.FindSingleComponent(&this.configurer); err != nil {
+			return fmt.Errorf("Bad context setup. Failed to FindSingleComponent ComponentConfigurer: %v", err)
+		}
+
+		if err := v.FindSingleComponent(&this.ctx); err != nil {
+			return fmt.Errorf("Bad context setup. Failed to FindSingleComponent ConfiguredContext: %v", err)
+		}
+
+		return nil
+	}
+
+	return errors.New("Unsupported context type")
+}
+
+//Binder must run before TwoPhaseInitializer calls PreInit
+func (this *ConfigBinder) Order() int {
+	return -100
+}
+
+func (this *ConfigBinder) OnPrepareComponent(c *ComponentImpl) error {
+	section := c.Tags().Get("config")
+	if section == "" {
+		return nil
+	}
+
+	props, err := this.lookupSection(section)
+	if err != nil {
+		return fmt.Errorf("Unable to configure %v from section '%v': %v", c.ty, section, err)
+	}
+
+	if err := bindProperties(reflect.ValueOf(c.inst), props, section); err != nil {
+		return fmt.Errorf("Unable to configure %v from section '%v': %v", c.ty, section, err)
+	}
+
+	return nil
+}
+
+func (this *ConfigBinder) OnComponentReady(c *ComponentImpl) error {
+	return nil
+}
+
+func (this *ConfigBinder) OnDestroyComponent(c *ComponentImpl) error {
+	return nil
+}
+
+//Merges section from every PropertySource. Later sources win
+func (this *ConfigBinder) lookupSection(name string) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+
+	for _, src := range this.ctx.FindComponentsByType(gPropertySourceType) {
+		if err := this.configurer.ConfigureComponent(src.(*ComponentImpl)); err != nil {
+			return nil, err
+		}
+
+		v, ok := src.Instance().(PropertySource).Property(name)
+		if !ok {
+			continue
+		}
+
+		section, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Property %v is not a section", name)
+		}
+
+		mergeProperties(r, section)
+	}
+
+	return r, nil
+}
+
+var gDurationType reflect.Type = reflect.TypeOf(time.Duration(0))
+
+//Binds property value onto target. Path is used for error reporting only
+func bindProperties(dst reflect.Value, src interface{}, path string) error {
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return bindProperties(dst.Elem(), src, path)
+	}
+
+	if dst.Type() == gDurationType {
+		return bindDuration(dst, src, path)
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		section, ok := src.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Property %v: section expected, got %T", path, src)
+		}
+		return bindStruct(dst, section, path)
+
+	case reflect.Map:
+		section, ok := src.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("Property %v: cannot bind %T to %v", path, src, dst.Type())
+		}
+		m := reflect.MakeMap(dst.Type())
+		for k, v := range section {
+			e := reflect.New(dst.Type().Elem()).Elem()
+			if err := bindProperties(e, v, path+"."+k); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), e)
+		}
+		dst.Set(m)
+
+	case reflect.Slice:
+		list, ok := src.([]interface{})
+		if !ok {
+			return fmt.Errorf("Property %v: list expected, got %T", path, src)
+		}
+		s := reflect.MakeSlice(dst.Type(), len(list), len(list))
+		for i, v := range list {
+			if err := bindProperties(s.Index(i), v, fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+
+	case reflect.Interface:
+		if src != nil && !reflect.TypeOf(src).AssignableTo(dst.Type()) {
+			return fmt.Errorf("Property %v: cannot bind %T to %v", path, src, dst.Type())
+		}
+		if src != nil {
+			dst.Set(reflect.ValueOf(src))
+		}
+
+	default:
+		return bindScalar(dst, src, path)
+	}
+
+	return nil
+}
+
+func bindStruct(dst reflect.Value, section map[string]interface{}, path string) error {
+	ty := dst.Type()
+
+	for i := 0; i < ty.NumField(); i++ {
+		f := ty.Field(i)
+		if f.PkgPath != "" { //Skip private fields
+			continue
+		}
+
+		name, opts := parseConfigTag(f.Tag.Get("config"))
+		if name == "-" {
+			continue
+		}
+
+		//Embedded structs share section with outer struct
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			if err := bindStruct(dst.Field(i), section, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		v, ok := findProperty(section, name)
+		if !ok {
+			if opts["required"] {
+				return fmt.Errorf("Property %v.%v is required", path, name)
+			}
+			continue
+		}
+
+		if err := bindProperties(dst.Field(i), v, path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func bindDuration(dst reflect.Value, src interface{}, path string) error {
+	if s, ok := src.(string); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("Property %v: %v", path, err)
+		}
+		dst.SetInt(int64(d))
+		return nil
+	}
+	return bindScalar(dst, src, path)
+}
+
+func bindScalar(dst reflect.Value, src interface{}, path string) error {
+	s := fmt.Sprint(src)
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(s)
+		return nil
+
+	case reflect.Bool:
+		if b, ok := src.(bool); ok {
+			dst.SetBool(b)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("Property %v: %v", path, err)
+		}
+		dst.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f, ok := src.(float64); ok { //JSON numbers
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		n, err := strconv.ParseInt(s, 10, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Property %v: %v", path, err)
+		}
+		dst.SetInt(n)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := src.(float64); ok {
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		n, err := strconv.ParseUint(s, 10, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Property %v: %v", path, err)
+		}
+		dst.SetUint(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("Property %v: %v", path, err)
+		}
+		dst.SetFloat(n)
+		return nil
+	}
+
+	return fmt.Errorf("Property %v: unsupported field type %v", path, dst.Type())
+}
+
+//Looks up key exactly, then ignoring case, '-' and '_'
+func findProperty(section map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := section[name]; ok {
+		return v, true
+	}
+
+	norm := normalizePropertyName(name)
+	for k, v := range section {
+		if normalizePropertyName(k) == norm {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func normalizePropertyName(name string) string {
+	name = strings.Replace(name, "_", "", -1)
+	name = strings.Replace(name, "-", "", -1)
+	return strings.ToLower(name)
+}
+
+func parseConfigTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+
+	opts := make(map[string]bool)
+	for _, o := range parts[1:] {
+		opts[strings.TrimSpace(o)] = true
+	}
+
+	return strings.TrimSpace(parts[0]), opts
+}
@@ -0,0 +1,228 @@
+package wntr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+//Decodes configuration file content into a tree of properties
+type ConfigDecoder func([]byte) (map[string]interface{}, error)
+
+//Configuration file source component
+//
+//   On PreInit phase it loads <Name>.<ext> files from Paths and then
+//	merges <Name>-<profile>.<ext> files over them for every active profile.
+//	Only .json files are supported out of the box, import
+//	github.com/d-tar/wntr/configformats for .yaml, .yml and .toml
+//
+//   Loaded properties are exposed as PropertySource, so ConfigBinder
+//	can bind them onto components declared with `config` tag
+type ConfigFiles struct {
+	//Base name of configuration files. Default is "app"
+	Name string
+	//Directories to look configuration files in. Default is current directory
+	Paths []string
+	//Active profiles. Default is profiles of context, see ActiveProfiles
+	Profiles []string
+
+	props MapPropertySource
+	ctx   *MutableContext
+}
+
+//Wires configuration files support into declarative context
+//
+//   var app struct {
+//           wntr.EnableConfigFiles
+//           Db DatabaseConfig `config:"database"`
+//   }
+//
+//   app.ConfigFiles.Profiles = []string{"prod"}
+type EnableConfigFiles struct {
+	ConfigFiles  ConfigFiles
+	ConfigBinder ConfigBinder
+}
+
+func (*EnableConfigFiles) ModuleName() string {
+	return "config"
+}
+
+var _ LoadablePropertySource = (*ConfigFiles)(nil)
+var _ PreInitable = (*ConfigFiles)(nil)
+var _ ContextAware = (*ConfigFiles)(nil)
+var _ propertyPeeker = (*ConfigFiles)(nil)
+var _ Module = (*EnableConfigFiles)(nil)
+
+//Extensions are listed in loading order
+var gConfigExtensions = []string{".json"}
+
+var gConfigDecoders = map[string]ConfigDecoder{
+	".json": decodeJsonConfig,
+}
+
+//Guards registered decoders
+var gConfigDecodersLock sync.RWMutex
+
+//Registers decoder for configuration files with given extension, like ".ini"
+//
+//  Safe for concurrent use, usually called from init of format package
+func RegisterConfigDecoder(ext string, decoder ConfigDecoder) {
+	gConfigDecodersLock.Lock()
+	defer gConfigDecodersLock.Unlock()
+
+	if _, ok := gConfigDecoders[ext]; !ok {
+		gConfigExtensions = append(gConfigExtensions, ext)
+	}
+	gConfigDecoders[ext] = decoder
+}
+
+/* Implementation */
+
+func (this *ConfigFiles) PreInit() error {
+	return this.LoadProperties()
+}
+
+func (this *ConfigFiles) LoadProperties() error {
+	if this.props != nil {
+		return nil
+	}
+
+	props, err := this.readProperties()
+	if err != nil {
+		return err
+	}
+
+	this.props = props
+	return nil
+}
+
+//Returns loaded properties, or reads them without keeping, see MutableContext.Validate
+func (this *ConfigFiles) peekProperties() (PropertySource, error) {
+	if this.props != nil {
+		return this.props, nil
+	}
+	return this.readProperties()
+}
+
+func (this *ConfigFiles) readProperties() (MapPropertySource, error) {
+	props := make(map[string]interface{})
+	loaded := 0
+
+	names := []string{this.baseName()}
+	for _, profile := range this.ActiveProfiles() {
+		names = append(names, this.baseName()+"-"+profile)
+	}
+
+	exts, decoders := configDecoders()
+
+	for _, name := range names {
+		for _, dir := range this.searchPaths() {
+			for _, ext := range exts {
+				path := filepath.Join(dir, name+ext)
+
+				tree, err := loadConfigFile(path, decoders[ext])
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				log.Println("ConfigFiles: Loaded", path)
+				mergeProperties(props, tree)
+				loaded++
+			}
+		}
+	}
+
+	if loaded == 0 {
+		return nil, fmt.Errorf("No configuration files named %v found in %v", this.baseName(), this.searchPaths())
+	}
+
+	return props, nil
+}
+
+func (this *ConfigFiles) SetContext(c Context) error {
+	if v, ok := c.(*MutableContext); ok {
+		this.ctx = v
+	}
+	return nil
+}
+
+func (this *ConfigFiles) Property(name string) (interface{}, bool) {
+	return this.props.Property(name)
+}
+
+//Returns profiles used to look up override files
+func (this *ConfigFiles) ActiveProfiles() []string {
+	if this.Profiles != nil {
+		return this.Profiles
+	}
+	if this.ctx != nil {
+		return this.ctx.ActiveProfiles()
+	}
+	return profilesFromEnv()
+}
+
+func (this *ConfigFiles) baseName() string {
+	if this.Name == "" {
+		return "app"
+	}
+	return this.Name
+}
+
+func (this *ConfigFiles) searchPaths() []string {
+	if len(this.Paths) == 0 {
+		return []string{"."}
+	}
+	return this.Paths
+}
+
+func profilesFromEnv() []string {
+	r := make([]string, 0)
+	for _, p := range strings.Split(os.Getenv("WNTR_PROFILES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+func loadConfigFile(path string, decoder ConfigDecoder) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := decoder(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse configuration file %v: %v", path, err)
+	}
+
+	return tree, nil
+}
+
+func decodeJsonConfig(data []byte) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//Returns copy of registered extensions, in loading order, and their decoders
+func configDecoders() ([]string, map[string]ConfigDecoder) {
+	gConfigDecodersLock.RLock()
+	defer gConfigDecodersLock.RUnlock()
+
+	decoders := make(map[string]ConfigDecoder, len(gConfigDecoders))
+	for ext, d := range gConfigDecoders {
+		decoders[ext] = d
+	}
+	return append([]string{}, gConfigExtensions...), decoders
+}
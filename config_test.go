@@ -0,0 +1,138 @@
+package wntr
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type DatabaseConfig struct {
+	Url      string `config:",required"`
+	MaxConns int    `config:"max_conns"`
+	Timeout  time.Duration
+	Replicas []string
+
+	validated bool
+}
+
+func (c *DatabaseConfig) PreInit() error {
+	//Values must be bound before PreInit
+	c.validated = c.Url != ""
+	return nil
+}
+
+type CacheConfig struct {
+	Size int
+}
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+//Decodes "section.key=value" lines
+func decodeKeyValueConfig(data []byte) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+	for _, line := range strings.Split(string(data), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		path := strings.Split(kv[0], ".")
+		section := r
+		for _, name := range path[:len(path)-1] {
+			if _, ok := section[name]; !ok {
+				section[name] = make(map[string]interface{})
+			}
+			section = section[name].(map[string]interface{})
+		}
+		section[path[len(path)-1]] = kv[1]
+	}
+	return r, nil
+}
+
+//Registers decoder for duration of test
+func registerTestConfigDecoder(t *testing.T, ext string, decoder ConfigDecoder) {
+	exts, decoders := configDecoders()
+	t.Cleanup(func() {
+		gConfigDecodersLock.Lock()
+		defer gConfigDecodersLock.Unlock()
+		gConfigExtensions, gConfigDecoders = exts, decoders
+	})
+
+	RegisterConfigDecoder(ext, decoder)
+}
+
+func TestConfigFilesBinding(t *testing.T) {
+	registerTestConfigDecoder(t, ".conf", decodeKeyValueConfig)
+
+	dir := writeConfigFiles(t, map[string]string{
+		"app.json":      `{"database": {"url": "postgres://localhost", "max_conns": 5, "timeout": "2s", "replicas": ["r1", "r2"]}}`,
+		"app.conf":      "cache.size=128\n",
+		"app-prod.json": `{"database": {"url": "postgres://prod", "max_conns": 50}}`,
+	})
+
+	var app struct {
+		EnableConfigFiles
+		Db    DatabaseConfig `config:"database"`
+		Cache CacheConfig    `config:"cache"`
+	}
+
+	app.ConfigFiles.Paths = []string{dir}
+	app.ConfigFiles.Profiles = []string{"prod"}
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if app.Db.Url != "postgres://prod" || app.Db.MaxConns != 50 {
+		t.Fatal("Profile override was not applied", app.Db)
+	}
+
+	if app.Db.Timeout != 2*time.Second || len(app.Db.Replicas) != 2 {
+		t.Fatal("Base configuration was not bound", app.Db)
+	}
+
+	if !app.Db.validated {
+		t.Fatal("Configuration was bound after PreInit")
+	}
+
+	if app.Cache.Size != 128 {
+		t.Fatal("Registered decoder was not used", app.Cache)
+	}
+
+	if v, ok := app.ConfigFiles.Property("database.url"); !ok || v != "postgres://prod" {
+		t.Fatal("Bad property lookup", v)
+	}
+}
+
+func TestConfigRequiredProperty(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.json": `{"database": {"max_conns": 5}}`,
+	})
+
+	var app struct {
+		EnableConfigFiles
+		Db DatabaseConfig `config:"database"`
+	}
+
+	app.ConfigFiles.Paths = []string{dir}
+	app.ConfigFiles.Profiles = []string{}
+
+	if _, err := FastBoot(&app); err == nil {
+		t.Fatal("Missing required property was not detected")
+	} else {
+		t.Log("Ok:", err)
+	}
+}
@@ -0,0 +1,73 @@
+//YAML and TOML configuration files
+//
+//   wntr.ConfigFiles reads only .json files, import this package
+//   to load .yaml, .yml and .toml files as well
+//
+//   import _ "github.com/d-tar/wntr/configformats"
+package configformats
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/d-tar/wntr"
+	"gopkg.in/yaml.v2"
+)
+
+func init() {
+	wntr.RegisterConfigDecoder(".yaml", DecodeYaml)
+	wntr.RegisterConfigDecoder(".yml", DecodeYaml)
+	wntr.RegisterConfigDecoder(".toml", DecodeToml)
+}
+
+//Decodes YAML document into a tree of properties
+func DecodeYaml(data []byte) (map[string]interface{}, error) {
+	var r map[string]interface{}
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return make(map[string]interface{}), nil
+	}
+	return normalizeProperties(r).(map[string]interface{}), nil
+}
+
+//Decodes TOML document into a tree of properties
+func DecodeToml(data []byte) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &r); err != nil {
+		return nil, err
+	}
+	return normalizeProperties(r).(map[string]interface{}), nil
+}
+
+/* Implementation */
+
+//Converts decoder specific containers into
+//map[string]interface{} sections and []interface{} lists
+func normalizeProperties(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeProperties(e)
+		}
+		return t
+	case map[interface{}]interface{}:
+		r := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			r[fmt.Sprint(k)] = normalizeProperties(e)
+		}
+		return r
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeProperties(e)
+		}
+		return t
+	case []map[string]interface{}:
+		r := make([]interface{}, len(t))
+		for i, e := range t {
+			r[i] = normalizeProperties(e)
+		}
+		return r
+	}
+	return v
+}
@@ -0,0 +1,61 @@
+package configformats
+
+import (
+	"github.com/d-tar/wntr"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type DatabaseConfig struct {
+	Url      string `config:",required"`
+	MaxConns int    `config:"max_conns"`
+	Timeout  time.Duration
+	Replicas []string
+}
+
+type CacheConfig struct {
+	Size int
+}
+
+func TestYamlAndTomlFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.yaml":     "database:\n  url: postgres://localhost\n  max_conns: 5\n  timeout: 2s\n  replicas: [r1, r2]\n",
+		"app.toml":     "[cache]\nsize = 128\n",
+		"app-prod.yml": "database:\n  url: postgres://prod\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var app struct {
+		wntr.EnableConfigFiles
+		Db    DatabaseConfig `config:"database"`
+		Cache CacheConfig    `config:"cache"`
+	}
+
+	app.ConfigFiles.Paths = []string{dir}
+	app.ConfigFiles.Profiles = []string{"prod"}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if app.Db.Url != "postgres://prod" || app.Db.MaxConns != 5 {
+		t.Fatal("YAML profile override was not applied", app.Db)
+	}
+
+	if app.Db.Timeout != 2*time.Second || len(app.Db.Replicas) != 2 {
+		t.Fatal("YAML configuration was not bound", app.Db)
+	}
+
+	if app.Cache.Size != 128 {
+		t.Fatal("TOML configuration was not bound", app.Cache)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"sync/atomic"
 )
 
 //Public contract for context
@@ -50,6 +51,7 @@ func NewContext() (c Context, e error) {
 func newMutableContext() *MutableContext {
 	c := &MutableContext{
 		components: make([]*ComponentImpl, 0),
+		registered: make(map[*ComponentImpl]bool),
 	}
 
 	c.RegisterComponent(c)
@@ -77,15 +79,24 @@ func FastDefaultContext(components ...interface{}) (Context, error) {
 
 //Struct implements default context
 type MutableContext struct {
-	components           []*ComponentImpl //List of registered components
+	components           []*ComponentImpl        //List of registered components
+	registered           map[*ComponentImpl]bool //Index of components
 	registrationHandlers []ComponentRegisterAware
+	conditional          []*conditionalComponent //Components registered on start if conditions are met
+	overrides            []*ComponentOverride    //Replacements applied on start
+	modules              []Module                //Started modules in dependency order
+	running              int32                   //Context has been started and not stopped yet, accessed atomically
+	parent               *MutableContext         //Context this one was forked from
+	children             []*MutableContext       //Contexts forked from this one
 }
 
 //Simple holder for registered components
 type ComponentImpl struct {
-	inst interface{}
-	ty   reflect.Type
-	tags string
+	inst   interface{}
+	ty     reflect.Type
+	tags   string
+	name   string
+	module string //Name of declaring module, empty for application components
 }
 
 type Component interface {
@@ -94,17 +105,70 @@ type Component interface {
 	Tags() reflect.StructTag
 }
 
+//Component registered with name, see RegisterNamedComponent
+type NamedComponent interface {
+	Component
+	Name() string
+}
+
+//Component declared by module, see Module
+type ModuleComponent interface {
+	Component
+	Module() string
+}
+
+//Returns name of component, empty if it has none
+func ComponentName(c Component) string {
+	if n, ok := c.(NamedComponent); ok {
+		return n.Name()
+	}
+	return ""
+}
+
+//Returns name of module declaring component, empty for application components
+func ComponentModule(c Component) string {
+	if m, ok := c.(ModuleComponent); ok {
+		return m.Module()
+	}
+	return ""
+}
+
 func (c *MutableContext) RegisterComponent(value interface{}) {
 	c.RegisterComponentWithTags(value, "")
 }
 
 func (c *MutableContext) RegisterComponentWithTags(value interface{}, tags string) {
+	c.RegisterNamedComponent("", value, tags)
+}
+
+//Registers component with name
+//  `name` tag, if present, takes precedence over passed name
+func (c *MutableContext) RegisterNamedComponent(name string, value interface{}, tags string) {
+	c.registerModuleComponent("", name, value, tags)
+}
+
+func (c *MutableContext) registerModuleComponent(module string, name string, value interface{}, tags string) {
+	if v := reflect.StructTag(tags).Get("name"); v != "" {
+		name = v
+	}
+
+	if hasConditions(tags) {
+		log.Println("Deferring conditional component ", reflect.TypeOf(value), "tags", tags)
+		c.conditional = append(c.conditional, &conditionalComponent{value, tags, name, module})
+		return
+	}
+
+	c.registerComponent(value, tags, name, module)
+}
+
+func (c *MutableContext) registerComponent(value interface{}, tags string, name string, module string) {
 	t := reflect.TypeOf(value)
-	log.Println("Registering component ", t, "tags", tags)
+	log.Println("Registering component ", t, "name", name, "module", module, "tags", tags)
 
-	comp := &ComponentImpl{value, t, tags}
+	comp := &ComponentImpl{value, t, tags, name, module}
 
 	c.components = append(c.components, comp)
+	c.registered[comp] = true
 
 	if v, ok := value.(ContextAware); ok {
 		if err := v.SetContext(c); err != nil {
@@ -123,22 +187,73 @@ func (c *MutableContext) RegisterComponentWithTags(value interface{}, tags strin
 }
 
 func (c *MutableContext) Start() error {
-	cnt := 0
+	if err := c.registerConditionalComponents(); err != nil {
+		return err
+	}
+
+	if err := c.applyOverrides(); err != nil {
+		return err
+	}
+
+	modules, err := sortModules(c.components)
+	if err != nil {
+		return err
+	}
+
+	started := make([]CtxEventHandler, 0)
 	for _, i := range c.components {
 		if v, ok := i.inst.(CtxEventHandler); ok {
+			//Failed processor may have configured some components already
+			started = append(started, v)
 			if err := v.OnStartContext(c); err != nil {
+				c.abortStart(started)
 				return err
 			}
-
-			cnt++
 		}
 	}
-	log.Println("Context started", cnt, "processors called")
+	log.Println("Context started", len(started), "processors called")
+
+	if err := startModules(modules); err != nil {
+		c.abortStart(started)
+		return err
+	}
+	c.modules = modules
+	atomic.StoreInt32(&c.running, 1)
 
 	return nil
 }
 
+//Destroys components configured by failed Start, Stop does nothing for context that is not running
+func (c *MutableContext) abortStart(started []CtxEventHandler) {
+	for _, v := range started {
+		if err := v.OnStopContext(c); err != nil {
+			log.Println("Failed to stop partially started context:", err)
+		}
+	}
+}
+
+//Stops forked children in reverse order, then context itself
+//
+//   Does nothing if context is not running, so it is safe to stop
+//   child context that was already stopped by its parent
 func (c *MutableContext) Stop() error {
+	if !atomic.CompareAndSwapInt32(&c.running, 1, 0) {
+		return nil
+	}
+
+	for i := len(c.children) - 1; i >= 0; i-- {
+		if err := c.children[i].Stop(); err != nil {
+			return err
+		}
+	}
+
+	if c.parent != nil {
+		c.parent.removeChild(c)
+	}
+
+	stopModules(c.modules)
+	c.modules = nil
+
 	cnt := 0
 	for _, i := range c.components {
 		if v, ok := i.inst.(CtxEventHandler); ok {
@@ -153,17 +268,45 @@ func (c *MutableContext) Stop() error {
 	return nil
 }
 
+//Finds components of type t, module private components are not included
 func (c *MutableContext) FindComponentsByType(t reflect.Type) []*ComponentImpl {
 	r := make([]*ComponentImpl, 0)
 
 	for _, v := range c.components {
-		if v.ty.AssignableTo(t) {
+		if v.ty.AssignableTo(t) && !isPrivateComponent(v) {
 			r = append(r, v)
 		}
 	}
 	return r
 }
 
+//Whether context has been started and not stopped yet
+func (c *MutableContext) Running() bool {
+	return atomic.LoadInt32(&c.running) == 1
+}
+
+func (c *MutableContext) removeChild(child *MutableContext) {
+	for i, v := range c.children {
+		if v == child {
+			c.children = append(c.children[:i], c.children[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *MutableContext) hasComponent(comp *ComponentImpl) bool {
+	return c.registered[comp]
+}
+
+//Returns all registered components in registration order
+func (c *MutableContext) Components() []Component {
+	r := make([]Component, len(c.components))
+	for i, comp := range c.components {
+		r[i] = comp
+	}
+	return r
+}
+
 func (c *MutableContext) FindSingleComponent(vptr interface{}) error {
 	t := reflect.TypeOf(vptr)
 
@@ -190,6 +333,9 @@ func (t *ComponentImpl) Instance() interface{} {
 	return t.inst
 }
 
+var _ NamedComponent = (*ComponentImpl)(nil)
+var _ ModuleComponent = (*ComponentImpl)(nil)
+
 func (t *ComponentImpl) Type() reflect.Type {
 	return t.ty
 }
@@ -197,3 +343,11 @@ func (t *ComponentImpl) Type() reflect.Type {
 func (t *ComponentImpl) Tags() reflect.StructTag {
 	return reflect.StructTag(t.tags)
 }
+
+func (t *ComponentImpl) Name() string {
+	return t.name
+}
+
+func (t *ComponentImpl) Module() string {
+	return t.module
+}
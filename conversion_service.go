@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"strconv"
+	"time"
 )
 
 type Converter interface {
@@ -15,12 +17,24 @@ type ConversionService interface {
 	Convert(interface{}, reflect.Type) (interface{}, error)
 }
 
+//Conversion service of injected converters
+//
+//   StandardConverters are tried first. Unless set before PreInit,
+//   they are StringConverters
 type GenericConversionService struct {
 	Converters         []Converter `inject:"a"`
 	StandardConverters []Converter
 }
 
 var _ ConversionService = (*GenericConversionService)(nil)
+var _ PreInitable = (*GenericConversionService)(nil)
+
+func (this *GenericConversionService) PreInit() error {
+	if this.StandardConverters == nil {
+		this.StandardConverters = StringConverters()
+	}
+	return nil
+}
 
 func (this *GenericConversionService) Convert(src interface{}, dstTy reflect.Type) (interface{}, error) {
 	srcTy := reflect.TypeOf(src)
@@ -82,7 +96,7 @@ func (b *converterBridgeImpl) Convert(from interface{}) (interface{}, error) {
 	var p1 interface{}
 	var p2 error
 
-	if !result[0].IsNil() {
+	if !isNilValue(result[0]) {
 		p1 = result[0].Interface()
 	}
 	if !result[1].IsNil() {
@@ -91,3 +105,87 @@ func (b *converterBridgeImpl) Convert(from interface{}) (interface{}, error) {
 
 	return p1, p2
 }
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+//Converters parsing string into bool, integer, float and time.Duration values
+func StringConverters() []Converter {
+	r := make([]Converter, 0, len(gStringConvertibleTypes))
+	for _, ty := range gStringConvertibleTypes {
+		r = append(r, &stringConverter{ty})
+	}
+	return r
+}
+
+var gStringType reflect.Type = reflect.TypeOf("")
+
+var gStringConvertibleTypes = []reflect.Type{
+	reflect.TypeOf(false),
+	reflect.TypeOf(int(0)), reflect.TypeOf(int8(0)), reflect.TypeOf(int16(0)), reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)),
+	reflect.TypeOf(uint(0)), reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)), reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)),
+	reflect.TypeOf(float32(0)), reflect.TypeOf(float64(0)),
+	gDurationType,
+}
+
+type stringConverter struct {
+	dstTy reflect.Type
+}
+
+func (c *stringConverter) Type() (reflect.Type, reflect.Type) {
+	return gStringType, c.dstTy
+}
+
+func (c *stringConverter) Convert(from interface{}) (interface{}, error) {
+	s := from.(string)
+	dst := reflect.New(c.dstTy).Elem()
+
+	var err error
+	switch {
+	case c.dstTy == gDurationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(s); err == nil {
+			dst.SetInt(int64(d))
+		}
+	case dst.Kind() == reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			dst.SetBool(b)
+		}
+	case dst.Kind() >= reflect.Int && dst.Kind() <= reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, c.dstTy.Bits()); err == nil {
+			dst.SetInt(n)
+		}
+	case dst.Kind() >= reflect.Uint && dst.Kind() <= reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, c.dstTy.Bits()); err == nil {
+			dst.SetUint(n)
+		}
+	case dst.Kind() == reflect.Float32 || dst.Kind() == reflect.Float64:
+		var n float64
+		if n, err = strconv.ParseFloat(s, c.dstTy.Bits()); err == nil {
+			dst.SetFloat(n)
+		}
+	default:
+		err = fmt.Errorf("unsupported type %v", c.dstTy)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot convert '%v' to %v: %v", s, c.dstTy, unwrapNumError(err))
+	}
+	return dst.Interface(), nil
+}
+
+//strconv errors repeat the input
+func unwrapNumError(err error) error {
+	if e, ok := err.(*strconv.NumError); ok {
+		return e.Err
+	}
+	return err
+}
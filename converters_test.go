@@ -3,6 +3,7 @@ package wntr
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 type ConvertedObj struct {
@@ -10,7 +11,7 @@ type ConvertedObj struct {
 }
 
 func FromStrConverter(v string) (*ConvertedObj, error) {
-	return &ConvertedObj{*v}, nil
+	return &ConvertedObj{v}, nil
 }
 
 func TestConversionService(t *testing.T) {
@@ -35,3 +36,50 @@ func TestConversionService(t *testing.T) {
 	t.Log(v, err)
 
 }
+
+func TestStringConverters(t *testing.T) {
+	conv := &GenericConversionService{}
+	if err := conv.PreInit(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		src string
+		dst interface{}
+	}{
+		{"42", int(42)},
+		{"-7", int8(-7)},
+		{"7", uint16(7)},
+		{"true", true},
+		{"2.5", float64(2.5)},
+		{"1m30s", 90 * time.Second},
+	}
+
+	for _, c := range cases {
+		v, err := conv.Convert(c.src, reflect.TypeOf(c.dst))
+		if err != nil || v != c.dst {
+			t.Fatal("Bad conversion of", c.src, v, err)
+		}
+	}
+
+	if _, err := conv.Convert("300", reflect.TypeOf(int8(0))); err == nil {
+		t.Fatal("Overflow was not detected")
+	}
+
+	if _, err := conv.Convert("abc", reflect.TypeOf(0)); err == nil {
+		t.Fatal("Bad number was converted")
+	}
+}
+
+type convertedId [2]byte
+
+func TestConverterBridgeValueResult(t *testing.T) {
+	c := ConverterBridge(func(s string) (convertedId, error) {
+		return convertedId{s[0], s[1]}, nil
+	})
+
+	v, err := c.Convert("ab")
+	if err != nil || v != (convertedId{'a', 'b'}) {
+		t.Fatal(v, err)
+	}
+}
@@ -0,0 +1,145 @@
+package wntr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Calculates run times of scheduled task
+type Schedule interface {
+	//Returns first run time after t, zero time if task never runs again
+	Next(t time.Time) time.Time
+}
+
+//Schedule with fixed delay between runs
+type Every time.Duration
+
+func (this Every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(this))
+}
+
+//Parses standard 5-field cron expression: minute hour day-of-month month day-of-week
+//
+//   Fields support '*', lists '1,15', ranges '1-5' and steps '*/10' or '0-30/5'.
+//   Day of week is 0-6 starting from Sunday, 7 is Sunday too.
+//   If both days of month and week are restricted, task runs when either matches
+func ParseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Bad cron expression '%v': expected 5 fields, got %v", expr, len(fields))
+	}
+
+	s := &cronSchedule{}
+	targets := []*cronField{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+
+	for i, f := range fields {
+		b := gCronBounds[i]
+		set, err := parseCronField(f, b[0], b[1])
+		if err != nil {
+			return nil, fmt.Errorf("Bad cron expression '%v': %v", expr, err)
+		}
+		*targets[i] = cronField{set, f == "*" || strings.HasPrefix(f, "*/")}
+	}
+
+	if s.dow.set[7] {
+		s.dow.set[0] = true
+	}
+
+	return s, nil
+}
+
+/* Implementation */
+
+var gCronBounds = [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+type cronField struct {
+	set [60]bool
+	any bool
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+}
+
+func parseCronField(f string, min, max int) ([60]bool, error) {
+	var set [60]bool
+
+	for _, part := range strings.Split(f, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			v, err := strconv.Atoi(part[i+1:])
+			if err != nil || v <= 0 {
+				return set, fmt.Errorf("bad step in '%v'", part)
+			}
+			rng, step = part[:i], v
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			v, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return set, fmt.Errorf("bad value in '%v'", part)
+			}
+			lo, hi = v, v
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return set, fmt.Errorf("bad range in '%v'", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return set, fmt.Errorf("'%v' is out of range %v-%v", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom.set[t.Day()]
+	dow := s.dow.set[int(t.Weekday())]
+
+	if !s.dom.any && !s.dow.any {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	//Expressions like "0 0 30 2 *" never fire
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !s.month.set[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hour.set[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minute.set[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
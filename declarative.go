@@ -50,6 +50,12 @@ func PopulateContextFromDefinitions(ctx Context, definitions ...interface{}) err
 }
 
 func populateComponents(ctx Context, def interface{}) error {
+	return populateModule(ctx, def, "", "")
+}
+
+//Registers definitions of def as components of module
+//  If def is a Module itself, its components belong to it
+func populateModule(ctx Context, def interface{}, name string, module string) error {
 
 	v := reflect.ValueOf(def)
 	t := v.Type()
@@ -68,9 +74,11 @@ func populateComponents(ctx Context, def interface{}) error {
 		return nil
 	}
 
-	ctx.RegisterComponent(v.Interface())
+	module = declaredModule(def, module)
+
+	registerDeclaredComponent(ctx, name, "", v.Interface(), module)
 
-	log.Println("StructContext: Registering definitions from ", t, "...")
+	log.Println("StructContext: Registering definitions from ", t, "module", module, "...")
 
 	for i := 0; i < t.NumField(); i++ {
 		fld := t.Field(i)
@@ -80,7 +88,7 @@ func populateComponents(ctx Context, def interface{}) error {
 			fldVal := v.Elem().Field(i)
 			ptrToFld := fldVal.Interface()
 			if ptrToFld != nil {
-				ctx.RegisterComponentWithTags(ptrToFld, string(fld.Tag))
+				registerDeclaredComponent(ctx, fld.Name, string(fld.Tag), ptrToFld, module)
 			}
 		}
 
@@ -92,14 +100,32 @@ func populateComponents(ctx Context, def interface{}) error {
 		ptrToFld := fldVal.Addr().Interface()
 
 		if fld.Anonymous {
-			if err := populateComponents(ctx, ptrToFld); err != nil {
+			if err := populateModule(ctx, ptrToFld, "", module); err != nil {
+				return err
+			}
+			continue
+		}
+
+		//Nested modules declare their own components
+		if _, ok := ptrToFld.(Module); ok {
+			if err := populateModule(ctx, ptrToFld, fld.Name, module); err != nil {
 				return err
 			}
 			continue
 		}
 
-		ctx.RegisterComponentWithTags(ptrToFld, string(fld.Tag))
+		registerDeclaredComponent(ctx, fld.Name, string(fld.Tag), ptrToFld, module)
 	}
 
 	return nil
 }
+
+//Declared components are named after their fields
+func registerDeclaredComponent(ctx Context, name string, tags string, value interface{}, module string) {
+	if v, ok := ctx.(*MutableContext); ok {
+		v.registerModuleComponent(module, name, value, tags)
+		return
+	}
+
+	ctx.RegisterComponentWithTags(value, tags)
+}
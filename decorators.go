@@ -0,0 +1,124 @@
+package wntr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+//InjectionWrapper that applies decorator functions to components
+//injected by interface type
+//
+//   Decorator is a component of func type `func(inner I) I`
+//   or `func(inner I) (I, error)`, usually declared as a func field
+//   of separate configuration, so declaration of decorated component is untouched
+//
+//   var decorators struct {
+//           Decorators wntr.DecoratorProcessor
+//           Caching    func(inner Dao) Dao
+//           Metrics    func(inner Dao) Dao
+//   }
+//
+//   Decorators of I are applied to every component injected as I,
+//   including elements of `inject:"all"` fields. Every consumer gets
+//   the same decorated value. Decorators are applied in declaration order,
+//   or by Ordered implemented by named func types, the first one is wrapped
+//   by the following ones. Decorators are applied before interceptors,
+//   so proxies wrap decorated values
+type DecoratorProcessor struct {
+	ctx       ConfiguredContext
+	decorated map[decoratedKey]interface{}
+}
+
+type decoratedKey struct {
+	comp  Component
+	iface reflect.Type
+}
+
+func _() {
+	var _ InjectionWrapper = &DecoratorProcessor{}
+	var _ Ordered = &DecoratorProcessor{}
+}
+
+/* Implementation */
+
+var gAnyType reflect.Type = reflect.TypeOf((*interface{})(nil)).Elem()
+var gErrorType reflect.Type = reflect.TypeOf((*error)(nil)).Elem()
+
+func (this *DecoratorProcessor) SetContext(c Context) error {
+	if v, ok := c.(*MutableContext); ok {
+		if err := v.FindSingleComponent(&this.ctx); err != nil {
+			return fmt.Errorf("Bad context setup. Failed to FindSingleComponent ConfiguredContext: %v", err)
+		}
+		return nil
+	}
+
+	return errors.New("Unsupported context type")
+}
+
+//Decorators go before InterceptorProcessor
+func (this *DecoratorProcessor) Order() int {
+	return -100
+}
+
+func (this *DecoratorProcessor) WrapInjection(value interface{}, target Component, iface reflect.Type) (interface{}, error) {
+	key := decoratedKey{target, iface}
+	if v, ok := this.decorated[key]; ok {
+		return v, nil
+	}
+
+	for _, d := range this.findDecorators(iface) {
+		out := d.Call([]reflect.Value{reflect.ValueOf(value)})
+
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, fmt.Errorf("Decorator %v failed: %v", d.Type(), out[1].Interface())
+		}
+		if out[0].IsNil() {
+			return nil, fmt.Errorf("Decorator %v returned nil", d.Type())
+		}
+
+		value = out[0].Interface()
+	}
+
+	if this.decorated == nil {
+		this.decorated = make(map[decoratedKey]interface{})
+	}
+	this.decorated[key] = value
+	return value, nil
+}
+
+//Returns decorator funcs of iface sorted by order
+func (this *DecoratorProcessor) findDecorators(iface reflect.Type) []reflect.Value {
+	comps := this.ctx.FindComponentsByType(gAnyType)
+	sortByOrder(comps)
+
+	r := make([]reflect.Value, 0)
+	for _, c := range comps {
+		if fn, ok := decoratorFunc(c.Instance(), iface); ok {
+			r = append(r, fn)
+		}
+	}
+	return r
+}
+
+//Accepts func(I) I and func(I) (I, error) values or pointers to them
+func decoratorFunc(inst interface{}, iface reflect.Type) (reflect.Value, bool) {
+	v := reflect.ValueOf(inst)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Func {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return v, false
+	}
+
+	t := v.Type()
+	if t.NumIn() != 1 || t.In(0) != iface || t.NumOut() < 1 || t.NumOut() > 2 || t.Out(0) != iface {
+		return v, false
+	}
+	if t.NumOut() == 2 && t.Out(1) != gErrorType {
+		return v, false
+	}
+
+	return v, true
+}
@@ -0,0 +1,73 @@
+package wntr
+
+import (
+	"reflect"
+	"testing"
+)
+
+type LoggingDao struct {
+	inner Dao2
+	name  string
+	log   *[]string
+}
+
+func (d *LoggingDao) DoJob() {
+	*d.log = append(*d.log, d.name)
+	d.inner.DoJob()
+}
+
+type metricsDecorator func(inner Dao2) Dao2
+
+func (metricsDecorator) Order() int {
+	return 2
+}
+
+type cachingDecorator func(inner Dao2) Dao2
+
+func (cachingDecorator) Order() int {
+	return 1
+}
+
+func TestDecorators(t *testing.T) {
+	var app struct {
+		Dao  DaoImpl2
+		Ctrl Controller
+		All  AllDaoStruct
+	}
+
+	var calls []string
+	decorations := 0
+	decorate := func(name string) func(Dao2) Dao2 {
+		return func(inner Dao2) Dao2 {
+			decorations++
+			return &LoggingDao{inner, name, &calls}
+		}
+	}
+
+	var decorators struct {
+		Decorators DecoratorProcessor
+		Metrics    metricsDecorator
+		Caching    cachingDecorator
+	}
+	decorators.Metrics = decorate("metrics")
+	decorators.Caching = decorate("caching")
+
+	ctx, err := FastBoot(&app, &decorators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	app.Ctrl.Dao.DoJob()
+	if !reflect.DeepEqual(calls, []string{"metrics", "caching"}) {
+		t.Fatal("Decorators were not applied in order", calls)
+	}
+
+	if len(app.All.Dao) != 1 || app.All.Dao[0] == &app.Dao {
+		t.Fatal("inject:all elements were not decorated", app.All.Dao)
+	}
+
+	if app.All.Dao[0] != app.Ctrl.Dao || decorations != 2 {
+		t.Fatal("Component was decorated for every consumer", decorations)
+	}
+}
@@ -6,14 +6,26 @@ import (
 	"reflect"
 )
 
+//Lifecycle of child context
+//
+//   Components are looked up in child context first, then in
+//   parent context and so on up to the root context.
+//
+//   Child component shadows ancestor components registered with
+//   the same name and type, or with the name set by `name` tag,
+//   so they are never resolved from child context.
+//
+//   Parent components are configured and destroyed by parent
+//   context only, so stopping child does not affect its parent
 type ForkedLifecycle struct {
 	*StandardLifecycle
 	Parent ConfiguredContext
 }
 
-var _ ConfiguredContext = (*ForkedLifecycle)(nil)
+var _ HierarchicalConfiguredContext = (*ForkedLifecycle)(nil)
 var _ CtxEventHandler = (*ForkedLifecycle)(nil)
 
+//Returns components of the nearest context that has any
 func (this *ForkedLifecycle) FindComponentsByType(t reflect.Type) []Component {
 	comps := this.StandardLifecycle.FindComponentsByType(t)
 
@@ -25,7 +37,96 @@ func (this *ForkedLifecycle) FindComponentsByType(t reflect.Type) []Component {
 	log.Println("Falling back to parent")
 
 	//Otherwise, let's ask for components our parent
-	return this.Parent.FindComponentsByType(t)
+	return this.withoutShadowed(this.Parent.FindComponentsByType(t))
+}
+
+func (this *ForkedLifecycle) FindComponentsVisibleTo(t reflect.Type, requester Component) []Component {
+	return this.lookup().visibleTo(t, requester)
+}
+
+//Returns components of child context followed by components of all ancestors
+func (this *ForkedLifecycle) FindAllComponentsVisibleTo(t reflect.Type, requester Component) []Component {
+	return this.lookup().allVisibleTo(t, requester)
+}
+
+func (this *ForkedLifecycle) ParentContext() ConfiguredContext {
+	return this.Parent
+}
+
+func (this *ForkedLifecycle) lookup() *componentLookup {
+	return &componentLookup{this.ctx.components, this.Parent}
+}
+
+func (this *ForkedLifecycle) withoutShadowed(comps []Component) []Component {
+	return this.lookup().withoutShadowed(comps)
+}
+
+//Resolves injection candidates among components and ancestor contexts,
+//shared by AutowiringProcessor, through lifecycles, and Validate
+type componentLookup struct {
+	comps  []*ComponentImpl
+	parent ConfiguredContext //nil in root context
+}
+
+//Returns local components visible to requester, or parent ones if there are none
+func (this *componentLookup) visibleTo(t reflect.Type, requester Component) []Component {
+	comps := toComponents(visibleComponents(this.comps, t, requester))
+
+	if len(comps) > 0 || this.parent == nil {
+		return comps
+	}
+
+	//Private components of parent are never visible to child
+	return this.withoutShadowed(this.parent.FindComponentsByType(t))
+}
+
+//Returns local components visible to requester followed by components of all ancestors
+func (this *componentLookup) allVisibleTo(t reflect.Type, requester Component) []Component {
+	comps := toComponents(visibleComponents(this.comps, t, requester))
+
+	if this.parent == nil {
+		return comps
+	}
+
+	var inherited []Component
+	if p, ok := this.parent.(HierarchicalConfiguredContext); ok {
+		inherited = p.FindAllComponentsVisibleTo(t, nil)
+	} else {
+		inherited = this.parent.FindComponentsByType(t)
+	}
+
+	return append(comps, this.withoutShadowed(inherited)...)
+}
+
+func (this *componentLookup) withoutShadowed(comps []Component) []Component {
+	r := make([]Component, 0, len(comps))
+	for _, c := range comps {
+		if this.shadows(c) {
+			log.Println("Component", ComponentName(c), "of parent context is shadowed")
+			continue
+		}
+		r = append(r, c)
+	}
+	return r
+}
+
+//Names default to field names, so they shadow components of the same type only,
+//unless set explicitly by `name` tag
+func (this *componentLookup) shadows(parent Component) bool {
+	name := ComponentName(parent)
+	if name == "" {
+		return false
+	}
+
+	for _, c := range this.comps {
+		if c.name != name {
+			continue
+		}
+		if c.ty == parent.Type() || c.Tags().Get("name") != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func NewForkedLifecycle(Parent ConfiguredContext) *ForkedLifecycle {
@@ -37,10 +138,20 @@ func NewForkedLifecycle(Parent ConfiguredContext) *ForkedLifecycle {
 
 var gConfiguredContextType reflect.Type = reflect.TypeOf((*ConfiguredContext)(nil)).Elem()
 
+//Creates child context of running context
+//
+//   Child is stopped automatically when parent is stopped
 func ForkContext(ctxToFork Context) (Context, error) {
-	ctx := newMutableContext()
+	mutCtx, ok := ctxToFork.(*MutableContext)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported context type %T", ctxToFork)
+	}
 
-	mutCtx := ctxToFork.(*MutableContext)
+	if !mutCtx.Running() {
+		return nil, fmt.Errorf("Cannot fork context that is not running")
+	}
+
+	ctx := newMutableContext()
 
 	comps := mutCtx.FindComponentsByType(gConfiguredContextType)
 
@@ -55,5 +166,8 @@ func ForkContext(ctxToFork Context) (Context, error) {
 	//Enable autowired
 	ctx.RegisterComponent(NewAutowiringProcessor())
 
+	ctx.parent = mutCtx
+	mutCtx.children = append(mutCtx.children, ctx)
+
 	return ctx, nil
 }
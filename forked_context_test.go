@@ -58,3 +58,187 @@ func TestReflect(t *testing.T) {
 
 	reflect.ValueOf(Trampoline).Call([]reflect.Value{v})
 }
+
+type HierarchyDao struct {
+	destroyed bool
+}
+
+func (d *HierarchyDao) DoJob() {
+}
+
+func (d *HierarchyDao) PreDestroy() {
+	d.destroyed = true
+}
+
+func forkOrFail(t *testing.T, parent Context, definitions ...interface{}) Context {
+	child, err := ForkContext(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PopulateContextFromDefinitions(child, definitions...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return child
+}
+
+func TestForkHierarchy(t *testing.T) {
+	var root struct {
+		Dao    HierarchyDao
+		Shared HierarchyDao
+	}
+
+	var middle struct {
+		Middle DaoImpl2
+	}
+
+	var leaf struct {
+		Dao   HierarchyDao //Shadows root's Dao
+		Found *DaoImpl2    `inject:"t"`
+		All   AllDaoStruct
+	}
+
+	rootCtx := ContextOrPanic(&root)
+	middleCtx := forkOrFail(t, rootCtx, &middle)
+	leafCtx := forkOrFail(t, middleCtx, &leaf)
+
+	if leaf.Found != &middle.Middle {
+		t.Fatal("Component was not resolved from parent", leaf.Found)
+	}
+
+	//Leaf's own Dao, middle's DaoImpl2 and root's Shared, but not shadowed root's Dao
+	if len(leaf.All.Dao) != 3 {
+		t.Fatal("Bad inject:all result across hierarchy", leaf.All.Dao)
+	}
+
+	for _, d := range leaf.All.Dao {
+		if d == &root.Dao {
+			t.Fatal("Shadowed parent component was injected")
+		}
+	}
+
+	leafCtx.Stop()
+	middleCtx.Stop()
+
+	if root.Dao.destroyed || root.Shared.destroyed {
+		t.Fatal("Stopping child context destroyed parent components")
+	}
+
+	if !leaf.Dao.destroyed {
+		t.Fatal("Child component was not destroyed")
+	}
+
+	rootCtx.Stop()
+
+	if !root.Shared.destroyed {
+		t.Fatal("Parent component was not destroyed")
+	}
+}
+
+func TestStopCascadesToChildren(t *testing.T) {
+	var root struct {
+		Dao HierarchyDao
+	}
+
+	var child1, child2 struct {
+		Dao HierarchyDao
+	}
+
+	rootCtx := ContextOrPanic(&root)
+	forkOrFail(t, rootCtx, &child1)
+	child2Ctx := forkOrFail(t, rootCtx, &child2)
+
+	grandChild, err := ForkContext(child2Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grandChild.Start()
+
+	rootCtx.Stop()
+
+	if !child1.Dao.destroyed || !child2.Dao.destroyed || !root.Dao.destroyed {
+		t.Fatal("Children were not stopped with parent")
+	}
+
+	if grandChild.(*MutableContext).Running() {
+		t.Fatal("Grandchild was not stopped with parent")
+	}
+
+	if _, err := ForkContext(rootCtx); err == nil {
+		t.Fatal("Forked context that is not running")
+	}
+}
+
+type CountingDao struct {
+	destroyed int
+}
+
+func (d *CountingDao) PreDestroy() {
+	d.destroyed++
+}
+
+func TestRepeatedStopDestroysOnce(t *testing.T) {
+	var root, child struct {
+		Dao CountingDao
+	}
+
+	rootCtx := ContextOrPanic(&root)
+	childCtx := forkOrFail(t, rootCtx, &child)
+
+	rootCtx.Stop()
+	childCtx.Stop()
+	rootCtx.Stop()
+
+	if root.Dao.destroyed != 1 || child.Dao.destroyed != 1 {
+		t.Fatal("Components were destroyed more than once", root.Dao.destroyed, child.Dao.destroyed)
+	}
+}
+
+type Dispatcher struct {
+}
+
+type DaoConsumer struct {
+	Dao *HierarchyDao `inject:"t"`
+}
+
+func TestForkShadowsByTypeOrExplicitName(t *testing.T) {
+	var root struct {
+		Dao HierarchyDao
+	}
+
+	var child struct {
+		Dao      Dispatcher //Unrelated type, root's Dao stays visible
+		Consumer DaoConsumer
+	}
+
+	rootCtx := ContextOrPanic(&root)
+	defer rootCtx.Stop()
+
+	childCtx := forkOrFail(t, rootCtx, &child)
+	defer childCtx.Stop()
+
+	if child.Consumer.Dao != &root.Dao {
+		t.Fatal("Parent component was shadowed by unrelated child component", child.Consumer.Dao)
+	}
+
+	var named struct {
+		Other    Dispatcher `name:"Dao"`
+		Consumer DaoConsumer
+	}
+
+	namedCtx, err := ForkContext(rootCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PopulateContextFromDefinitions(namedCtx, &named); err != nil {
+		t.Fatal(err)
+	}
+	if err := namedCtx.Start(); err == nil {
+		t.Fatal("Component shadowed by name tag was injected")
+	}
+}
@@ -0,0 +1,151 @@
+package wntr
+
+import (
+	"fmt"
+)
+
+//Health status of component or whole application
+type HealthStatus string
+
+const (
+	HealthUp       HealthStatus = "UP"
+	HealthDegraded HealthStatus = "DEGRADED"
+	HealthDown     HealthStatus = "DOWN"
+)
+
+//Result of single health check
+type Health struct {
+	Status  HealthStatus           `json:"status"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+//Component that reports its own health
+//
+//   Indicators are gathered by HealthService, every component
+//   implementing the interface takes part in health checks
+//
+//   func (d *Dao) HealthName() string { return "database" }
+//
+//   func (d *Dao) Health() wntr.Health {
+//           if err := d.db.Ping(); err != nil {
+//                   return wntr.Down(err)
+//           }
+//           return wntr.Up()
+//   }
+type HealthIndicator interface {
+	HealthName() string
+	Health() Health
+}
+
+//Aggregated health of application
+type HealthReport struct {
+	Status     HealthStatus      `json:"status"`
+	Components map[string]Health `json:"components,omitempty"`
+}
+
+//Gathers health of every HealthIndicator in context
+//
+//   Aggregated status is DOWN if any indicator is DOWN,
+//   DEGRADED if any indicator is DEGRADED and UP otherwise.
+//   Indicator that panics is reported DOWN
+type HealthService struct {
+	Indicators []HealthIndicator `inject:"all"`
+
+	ctx Context
+}
+
+func _() {
+	var _ ContextAware = &HealthService{}
+}
+
+//Healthy status with optional details as key, value pairs
+func Up(details ...interface{}) Health {
+	return Health{HealthUp, healthDetails(details)}
+}
+
+//Degraded status with optional details as key, value pairs
+func Degraded(details ...interface{}) Health {
+	return Health{HealthDegraded, healthDetails(details)}
+}
+
+//Failed status caused by err
+func Down(err error) Health {
+	return Health{HealthDown, map[string]interface{}{"error": err.Error()}}
+}
+
+/* Implementation */
+
+func (this *HealthService) SetContext(c Context) error {
+	this.ctx = c
+	return nil
+}
+
+//Checks every indicator and aggregates results
+func (this *HealthService) Check() HealthReport {
+	r := HealthReport{
+		Status:     HealthUp,
+		Components: make(map[string]Health),
+	}
+
+	for _, ind := range this.Indicators {
+		h := checkIndicator(ind)
+		r.Components[ind.HealthName()] = h
+		r.Status = worstStatus(r.Status, h.Status)
+	}
+
+	return r
+}
+
+//Process is alive as long as it is able to answer
+func (this *HealthService) Liveness() HealthReport {
+	return HealthReport{Status: HealthUp}
+}
+
+//Application is ready when context is started and no indicator is DOWN
+func (this *HealthService) Readiness() HealthReport {
+	if v, ok := this.ctx.(interface {
+		Running() bool
+	}); ok && !v.Running() {
+		return HealthReport{
+			Status:     HealthDown,
+			Components: map[string]Health{"context": {HealthDown, map[string]interface{}{"error": "context is not started"}}},
+		}
+	}
+
+	return this.Check()
+}
+
+func checkIndicator(ind HealthIndicator) (h Health) {
+	defer func() {
+		if r := recover(); r != nil {
+			h = Down(fmt.Errorf("Health check panic: %v", r))
+		}
+	}()
+
+	h = ind.Health()
+	if h.Status == "" {
+		h.Status = HealthUp
+	}
+	return h
+}
+
+var gHealthSeverity = map[HealthStatus]int{HealthUp: 0, HealthDegraded: 1, HealthDown: 2}
+
+func worstStatus(a, b HealthStatus) HealthStatus {
+	if gHealthSeverity[b] > gHealthSeverity[a] {
+		return b
+	}
+	return a
+}
+
+func healthDetails(kv []interface{}) map[string]interface{} {
+	if len(kv) == 0 {
+		return nil
+	}
+
+	r := make(map[string]interface{})
+	for i := 0; i+1 < len(kv); i += 2 {
+		r[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	return r
+}
@@ -0,0 +1,84 @@
+package wntr
+
+import (
+	"errors"
+	"testing"
+)
+
+type CacheHealth struct {
+	status HealthStatus
+}
+
+func (c *CacheHealth) HealthName() string {
+	return "cache"
+}
+
+func (c *CacheHealth) Health() Health {
+	if c.status == HealthDown {
+		return Down(errors.New("connection refused"))
+	}
+	return Health{c.status, nil}
+}
+
+type PanickingHealth struct {
+}
+
+func (*PanickingHealth) HealthName() string {
+	return "broken"
+}
+
+func (*PanickingHealth) Health() Health {
+	panic("unexpected")
+}
+
+func TestHealthAggregation(t *testing.T) {
+	var app struct {
+		Health HealthService
+		Cache  CacheHealth
+	}
+	app.Cache.status = HealthDegraded
+
+	ctx, err := CreateComplexContext(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := app.Health.Readiness(); r.Status != HealthDown {
+		t.Fatal("Context is not started, but application is ready", r)
+	}
+
+	if err := ctx.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if r := app.Health.Readiness(); r.Status != HealthDegraded || r.Components["cache"].Status != HealthDegraded {
+		t.Fatal("Bad readiness", r)
+	}
+
+	app.Cache.status = HealthDown
+	if r := app.Health.Check(); r.Status != HealthDown || r.Components["cache"].Details["error"] != "connection refused" {
+		t.Fatal("Bad health", r)
+	}
+
+	if r := app.Health.Liveness(); r.Status != HealthUp {
+		t.Fatal("Bad liveness", r)
+	}
+}
+
+func TestHealthIndicatorPanic(t *testing.T) {
+	var app struct {
+		Health HealthService
+		Broken PanickingHealth
+	}
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if r := app.Health.Check(); r.Status != HealthDown {
+		t.Fatal("Panic was not reported", r)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"time"
 )
 
 //Marker interface to be implemented to
@@ -26,6 +27,22 @@ type ConfiguredContext interface {
 	FindComponentsByType(reflect.Type) []Component
 }
 
+//ConfiguredContext that resolves module private components
+//for components of the same module
+type ScopedConfiguredContext interface {
+	ConfiguredContext
+	FindComponentsVisibleTo(reflect.Type, Component) []Component
+}
+
+//ConfiguredContext that is a part of context hierarchy
+type HierarchicalConfiguredContext interface {
+	ScopedConfiguredContext
+	//Returns matching components of this context and all its ancestors
+	FindAllComponentsVisibleTo(reflect.Type, Component) []Component
+	//Returns parent context or nil for root one
+	ParentContext() ConfiguredContext
+}
+
 //Interface to be implemented by component
 //that needs some initialization before configuring by context
 type PreInitable interface {
@@ -46,7 +63,8 @@ type PreDestroyable interface {
 // 1st phase - is 'before component configured'
 // 2nd phase - is 'after component configured'
 //
-// Required to add ComponentLifecycle components to context
+// Required to add ComponentLifecycle components to context.
+// Processors are invoked in order, see Ordered
 type StandardLifecycle struct {
 	lifecycleProcessors []ComponentLifecycle
 	//table of current component states
@@ -54,23 +72,38 @@ type StandardLifecycle struct {
 	//list of components in acquisition order
 	//  * this is an inversion of disposition order
 	componentOrder []*ComponentImpl
+	//time spent configuring components
+	startupDurations map[*ComponentImpl]time.Duration
 
 	ctx *MutableContext
 }
 
+//Time spent configuring component, including configuration of its dependencies
+type ComponentStartup struct {
+	Component Component
+	Duration  time.Duration
+}
+
+//ConfiguredContext that measures component startup
+type StartupTimings interface {
+	ComponentStartups() []ComponentStartup
+}
+
 //Component that implements PreInitable and PostInitable interfaces behaviour
 type TwoPhaseInitializer struct {
 }
 
 func _() {
 	var _ ComponentLifecycle = &TwoPhaseInitializer{}
+	var _ StartupTimings = &StandardLifecycle{}
 }
 
 /* Implementation */
 
 func NewStandardLifecycle() *StandardLifecycle {
 	return &StandardLifecycle{
-		componentStates: make(map[*ComponentImpl]uint32),
+		componentStates:  make(map[*ComponentImpl]uint32),
+		startupDurations: make(map[*ComponentImpl]time.Duration),
 	}
 }
 
@@ -90,7 +123,15 @@ func (this *StandardLifecycle) SetContext(c Context) error {
 
 func (h *StandardLifecycle) OnComponentRegistered(c *ComponentImpl) {
 	if p, ok := c.inst.(ComponentLifecycle); ok {
-		h.lifecycleProcessors = append(h.lifecycleProcessors, p)
+		//Keep processors sorted by order, preserving registration order for equal ones
+		pos := len(h.lifecycleProcessors)
+		for pos > 0 && componentOrder(h.lifecycleProcessors[pos-1]) > componentOrder(p) {
+			pos--
+		}
+
+		h.lifecycleProcessors = append(h.lifecycleProcessors, nil)
+		copy(h.lifecycleProcessors[pos+1:], h.lifecycleProcessors[pos:])
+		h.lifecycleProcessors[pos] = p
 	}
 
 }
@@ -109,6 +150,13 @@ func (h *StandardLifecycle) OnStartContext(ctx *MutableContext) error {
 }
 
 func (h *StandardLifecycle) ConfigureComponent(c *ComponentImpl) error {
+	//Components of other contexts are configured by their own lifecycle
+	if !h.ctx.hasComponent(c) {
+		return nil
+	}
+
+	start := time.Now()
+
 	if s, ok := h.componentStates[c]; !ok {
 		h.componentStates[c] = stateResolving
 		log.Println("Start configuring", c.ty)
@@ -132,12 +180,22 @@ func (h *StandardLifecycle) ConfigureComponent(c *ComponentImpl) error {
 	}
 
 	h.componentStates[c] = stateResolved
+	h.startupDurations[c] = time.Since(start)
 	log.Println("Component configured", c.ty)
 	h.componentOrder = append(h.componentOrder, c)
 
 	return nil
 }
 
+//Returns startup durations in configuration order
+func (h *StandardLifecycle) ComponentStartups() []ComponentStartup {
+	r := make([]ComponentStartup, len(h.componentOrder))
+	for i, c := range h.componentOrder {
+		r[i] = ComponentStartup{c, h.startupDurations[c]}
+	}
+	return r
+}
+
 func (h *StandardLifecycle) OnStopContext(ctx *MutableContext) error {
 	eIdx := len(h.componentOrder) - 1
 
@@ -145,6 +203,7 @@ func (h *StandardLifecycle) OnStopContext(ctx *MutableContext) error {
 		c := h.componentOrder[eIdx-i]
 		h.deconstructComponent(c)
 	}
+	h.componentOrder = nil
 
 	return nil
 }
@@ -157,8 +216,23 @@ func (h *StandardLifecycle) deconstructComponent(c *ComponentImpl) {
 }
 
 func (h *StandardLifecycle) FindComponentsByType(t reflect.Type) []Component {
-	comps := h.ctx.FindComponentsByType(t)
+	return toComponents(h.ctx.FindComponentsByType(t))
+}
+
+func (h *StandardLifecycle) FindComponentsVisibleTo(t reflect.Type, requester Component) []Component {
+	return toComponents(h.ctx.FindComponentsVisibleTo(t, requester))
+}
+
+//Root context has no ancestors
+func (h *StandardLifecycle) FindAllComponentsVisibleTo(t reflect.Type, requester Component) []Component {
+	return h.FindComponentsVisibleTo(t, requester)
+}
+
+func (h *StandardLifecycle) ParentContext() ConfiguredContext {
+	return nil
+}
 
+func toComponents(comps []*ComponentImpl) []Component {
 	r := make([]Component, len(comps), len(comps))
 
 	for i, c := range comps {
@@ -0,0 +1,461 @@
+package wntr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Metric exposed by MetricsRegistry in Prometheus text format
+type Metric interface {
+	MetricName() string
+	WritePrometheus(w io.Writer)
+}
+
+//Monotonic counter
+//
+//   Declare it as a component, name, help and label names are taken
+//   from tags if not set explicitly
+//
+//   var app struct {
+//           Metrics wntr.EnableMetrics
+//           Orders  wntr.Counter `metric:"orders_total" help:"Orders placed" labels:"region"`
+//   }
+//
+//   app.Orders.Inc("eu")
+type Counter struct {
+	Name   string
+	Help   string
+	Labels []string
+
+	series metricSeries
+}
+
+//Value that goes up and down
+type Gauge struct {
+	Name   string
+	Help   string
+	Labels []string
+
+	series metricSeries
+}
+
+//Distribution of observed values over buckets
+//
+//  DefaultBuckets are used if Buckets are not set
+//  or `buckets:"0.1,0.5,1"` tag is not declared
+type Histogram struct {
+	Name    string
+	Help    string
+	Labels  []string
+	Buckets []float64
+
+	lock   sync.Mutex
+	keys   []string
+	values map[string]*histogramValue
+}
+
+//Default histogram buckets, suitable for durations in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//Collects metrics and renders them in Prometheus text format
+//
+//   Every Metric component of context is registered on PostInit,
+//   other metrics are added with Register or NewCounter, NewGauge and NewHistogram.
+//   Component startup durations are exposed as
+//   `wntr_component_startup_seconds` gauge
+type MetricsRegistry struct {
+	Ctx ConfiguredContext `inject:"t"`
+
+	lock    sync.Mutex
+	metrics map[string]Metric
+}
+
+//Metrics module
+type EnableMetrics struct {
+	Registry MetricsRegistry
+}
+
+func _() {
+	var _ Metric = &Counter{}
+	var _ Metric = &Gauge{}
+	var _ Metric = &Histogram{}
+	var _ PostInitable = &MetricsRegistry{}
+	var _ io.WriterTo = &MetricsRegistry{}
+	var _ Module = &EnableMetrics{}
+}
+
+func (*EnableMetrics) ModuleName() string {
+	return "metrics"
+}
+
+//Increments counter with label values
+func (this *Counter) Inc(labels ...string) {
+	this.Add(1, labels...)
+}
+
+//Adds non-negative delta to counter with label values
+func (this *Counter) Add(delta float64, labels ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("Counter %v cannot decrease", this.Name))
+	}
+	this.series.add(delta, labels)
+}
+
+func (this *Counter) MetricName() string {
+	return this.Name
+}
+
+func (this *Counter) WritePrometheus(w io.Writer) {
+	writeMetricHeader(w, this.Name, this.Help, "counter")
+	this.series.write(w, this.Name, this.Labels)
+}
+
+//Sets gauge value with label values
+func (this *Gauge) Set(value float64, labels ...string) {
+	this.series.set(value, labels)
+}
+
+//Adds delta to gauge value with label values
+func (this *Gauge) Add(delta float64, labels ...string) {
+	this.series.add(delta, labels)
+}
+
+func (this *Gauge) MetricName() string {
+	return this.Name
+}
+
+func (this *Gauge) WritePrometheus(w io.Writer) {
+	writeMetricHeader(w, this.Name, this.Help, "gauge")
+	this.series.write(w, this.Name, this.Labels)
+}
+
+//Observes value with label values
+func (this *Histogram) Observe(value float64, labels ...string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.Buckets == nil {
+		this.Buckets = DefaultBuckets
+	}
+	if this.values == nil {
+		this.values = make(map[string]*histogramValue)
+	}
+
+	key := seriesKey(labels)
+	v, ok := this.values[key]
+	if !ok {
+		v = &histogramValue{counts: make([]uint64, len(this.Buckets))}
+		this.values[key] = v
+		this.keys = append(this.keys, key)
+	}
+
+	for i, b := range this.Buckets {
+		if value <= b {
+			v.counts[i]++
+		}
+	}
+	v.sum += value
+	v.count++
+}
+
+func (this *Histogram) MetricName() string {
+	return this.Name
+}
+
+func (this *Histogram) WritePrometheus(w io.Writer) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	writeMetricHeader(w, this.Name, this.Help, "histogram")
+	for _, key := range this.keys {
+		v := this.values[key]
+		labels := strings.Split(key, gSeriesSeparator)
+
+		for i, b := range this.Buckets {
+			fmt.Fprintf(w, "%v_bucket%v %v\n", this.Name, formatLabels(this.Labels, labels, "le", formatFloat(b)), v.counts[i])
+		}
+		fmt.Fprintf(w, "%v_bucket%v %v\n", this.Name, formatLabels(this.Labels, labels, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", this.Name, formatLabels(this.Labels, labels), formatFloat(v.sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", this.Name, formatLabels(this.Labels, labels), v.count)
+	}
+}
+
+//Registers metric components declared in context
+func (this *MetricsRegistry) PostInit() error {
+	for _, c := range this.Ctx.FindComponentsByType(gMetricType) {
+		if err := applyMetricTags(c); err != nil {
+			return err
+		}
+		if err := this.Register(c.Instance().(Metric)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Adds metric to registry. Metric names must be unique
+func (this *MetricsRegistry) Register(m Metric) error {
+	_, err := this.registerOrReuse(m, func(Metric) bool { return false })
+	return err
+}
+
+//Creates and registers counter
+//
+//   Registered counter with the same name and labels is returned instead,
+//   so components of forked contexts sharing registry share their metrics
+func (this *MetricsRegistry) NewCounter(name, help string, labels ...string) (*Counter, error) {
+	m, err := this.registerOrReuse(&Counter{Name: name, Help: help, Labels: labels}, func(old Metric) bool {
+		c, ok := old.(*Counter)
+		return ok && equalStrings(c.Labels, labels)
+	})
+	c, _ := m.(*Counter)
+	return c, err
+}
+
+//Creates and registers gauge, reuses registered one like NewCounter
+func (this *MetricsRegistry) NewGauge(name, help string, labels ...string) (*Gauge, error) {
+	m, err := this.registerOrReuse(&Gauge{Name: name, Help: help, Labels: labels}, func(old Metric) bool {
+		g, ok := old.(*Gauge)
+		return ok && equalStrings(g.Labels, labels)
+	})
+	g, _ := m.(*Gauge)
+	return g, err
+}
+
+//Creates and registers histogram with buckets, DefaultBuckets are used if nil.
+//Reuses registered one with the same labels and buckets like NewCounter
+func (this *MetricsRegistry) NewHistogram(name, help string, buckets []float64, labels ...string) (*Histogram, error) {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	m, err := this.registerOrReuse(&Histogram{Name: name, Help: help, Labels: labels, Buckets: buckets}, func(old Metric) bool {
+		h, ok := old.(*Histogram)
+		return ok && equalStrings(h.Labels, labels) && reflect.DeepEqual(h.buckets(), buckets)
+	})
+	h, _ := m.(*Histogram)
+	return h, err
+}
+
+//Writes all metrics in Prometheus text format, sorted by name
+func (this *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+
+	this.lock.Lock()
+	names := make([]string, 0, len(this.metrics))
+	for name := range this.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]Metric, len(names))
+	for i, name := range names {
+		metrics[i] = this.metrics[name]
+	}
+	this.lock.Unlock()
+
+	for _, m := range metrics {
+		m.WritePrometheus(&b)
+	}
+
+	this.writeStartups(&b)
+
+	return b.WriteTo(w)
+}
+
+/* Implementation */
+
+//Registers metric, or returns registered metric of the same name if it is compatible
+func (this *MetricsRegistry) registerOrReuse(m Metric, compatible func(old Metric) bool) (Metric, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if m.MetricName() == "" {
+		return nil, fmt.Errorf("Metric %T has no name", m)
+	}
+
+	if this.metrics == nil {
+		this.metrics = make(map[string]Metric)
+	}
+
+	if old, ok := this.metrics[m.MetricName()]; ok && old != m {
+		if compatible(old) {
+			return old, nil
+		}
+		return nil, fmt.Errorf("Metric %v is registered twice", m.MetricName())
+	}
+
+	this.metrics[m.MetricName()] = m
+	return m, nil
+}
+
+func (this *Histogram) buckets() []float64 {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.Buckets == nil {
+		return DefaultBuckets
+	}
+	return this.Buckets
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var gMetricType reflect.Type = reflect.TypeOf((*Metric)(nil)).Elem()
+
+const gSeriesSeparator = "\xff"
+
+//Values of metric by label values
+type metricSeries struct {
+	lock   sync.Mutex
+	keys   []string
+	values map[string]float64
+}
+
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func seriesKey(labels []string) string {
+	return strings.Join(labels, gSeriesSeparator)
+}
+
+func (this *metricSeries) update(labels []string, f func(float64) float64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.values == nil {
+		this.values = make(map[string]float64)
+	}
+
+	key := seriesKey(labels)
+	v, ok := this.values[key]
+	if !ok {
+		this.keys = append(this.keys, key)
+	}
+	this.values[key] = f(v)
+}
+
+func (this *metricSeries) add(delta float64, labels []string) {
+	this.update(labels, func(v float64) float64 { return v + delta })
+}
+
+func (this *metricSeries) set(value float64, labels []string) {
+	this.update(labels, func(float64) float64 { return value })
+}
+
+func (this *metricSeries) write(w io.Writer, name string, names []string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, key := range this.keys {
+		labels := strings.Split(key, gSeriesSeparator)
+		fmt.Fprintf(w, "%v%v %v\n", name, formatLabels(names, labels), formatFloat(this.values[key]))
+	}
+}
+
+func (this *MetricsRegistry) writeStartups(w io.Writer) {
+	timings, ok := this.Ctx.(StartupTimings)
+	if !ok {
+		return
+	}
+
+	writeMetricHeader(w, "wntr_component_startup_seconds", "Time spent configuring component, including its dependencies", "gauge")
+	for _, s := range timings.ComponentStartups() {
+		labels := formatLabels([]string{"component", "type"}, []string{ComponentName(s.Component), s.Component.Type().String()})
+		fmt.Fprintf(w, "wntr_component_startup_seconds%v %v\n", labels, formatFloat(s.Duration.Seconds()))
+	}
+}
+
+//Fills empty metric fields from `metric`, `help`, `labels` and `buckets` tags
+func applyMetricTags(c Component) error {
+	v := reflect.ValueOf(c.Instance())
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	st := v.Elem()
+	tags := c.Tags()
+
+	setString := func(field, tag string) {
+		if f := st.FieldByName(field); f.IsValid() && f.String() == "" {
+			f.SetString(tags.Get(tag))
+		}
+	}
+	setString("Name", "metric")
+	setString("Help", "help")
+
+	if f := st.FieldByName("Labels"); f.IsValid() && f.Len() == 0 && tags.Get("labels") != "" {
+		f.Set(reflect.ValueOf(strings.Split(tags.Get("labels"), ",")))
+	}
+
+	if f := st.FieldByName("Buckets"); f.IsValid() && f.Len() == 0 && tags.Get("buckets") != "" {
+		buckets := make([]float64, 0)
+		for _, s := range strings.Split(tags.Get("buckets"), ",") {
+			b, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return fmt.Errorf("Bad buckets of metric %v: %v", ComponentName(c), err)
+			}
+			buckets = append(buckets, b)
+		}
+		f.Set(reflect.ValueOf(buckets))
+	}
+
+	return nil
+}
+
+func writeMetricHeader(w io.Writer, name, help, kind string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %v %v\n", name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(help))
+	}
+	fmt.Fprintf(w, "# TYPE %v %v\n", name, kind)
+}
+
+//Renders {name="value",...}, extra holds additional name, value pair
+func formatLabels(names []string, values []string, extra ...string) string {
+	pairs := make([]string, 0)
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, fmt.Sprintf("%v=%v", name, quoteLabel(value)))
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", extra[0], quoteLabel(extra[1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func quoteLabel(v string) string {
+	return `"` + strings.NewReplacer("\\", `\\`, "\n", `\n`, `"`, `\"`).Replace(v) + `"`
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,64 @@
+package wntr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	var app struct {
+		Metrics EnableMetrics
+		Orders  Counter   `metric:"orders_total" help:"Orders placed" labels:"region"`
+		Queue   Gauge     `metric:"queue_size"`
+		Latency Histogram `metric:"latency_seconds" buckets:"0.1,1"`
+	}
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	app.Orders.Inc("eu")
+	app.Orders.Add(2, "us")
+	app.Queue.Set(5)
+	app.Latency.Observe(0.5)
+
+	var b bytes.Buffer
+	if _, err := app.Metrics.Registry.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	t.Log(out)
+
+	expected := []string{
+		"# HELP orders_total Orders placed\n# TYPE orders_total counter\n",
+		"orders_total{region=\"eu\"} 1\n",
+		"orders_total{region=\"us\"} 2\n",
+		"queue_size 5\n",
+		"latency_seconds_bucket{le=\"0.1\"} 0\n",
+		"latency_seconds_bucket{le=\"1\"} 1\n",
+		"latency_seconds_bucket{le=\"+Inf\"} 1\n",
+		"latency_seconds_count 1\n",
+		"wntr_component_startup_seconds{component=\"Orders\",type=\"*wntr.Counter\"}",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Fatal("Expected output not found:", line)
+		}
+	}
+
+	if err := app.Metrics.Registry.Register(&Counter{Name: "orders_total"}); err == nil {
+		t.Fatal("Duplicate metric was registered")
+	}
+
+	if c, err := app.Metrics.Registry.NewCounter("orders_total", "", "region"); err != nil || c != &app.Orders {
+		t.Fatal("Registered counter was not reused", c, err)
+	}
+
+	if _, err := app.Metrics.Registry.NewGauge("orders_total", ""); err == nil {
+		t.Fatal("Metric of other type was reused")
+	}
+}
@@ -0,0 +1,202 @@
+package wntr
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+)
+
+//Reusable configuration bundle
+//
+//   Module is a declarative configuration struct with a name.
+//   Components declared in module are exported by default, so they can
+//   be injected anywhere. Components tagged `module:"private"` are visible
+//   to components of the same module only
+//
+//   type MetricsModule struct {
+//           Registry  MetricsRegistry
+//           Collector RuntimeCollector `module:"private"`
+//   }
+//
+//   func (*MetricsModule) ModuleName() string { return "metrics" }
+//
+//   var app struct {
+//           webmvc.EnableDefaultWebMvc
+//           Metrics MetricsModule
+//   }
+type Module interface {
+	ModuleName() string
+}
+
+//Module that requires other modules to be declared in context
+type DependentModule interface {
+	Module
+	ModuleDependencies() []string
+}
+
+//Module hook called after context has started
+//
+//  Modules are started in dependency order
+type ModuleStartable interface {
+	OnModuleStart() error
+}
+
+//Module hook called before context is stopped
+//
+//  Modules are stopped in reverse dependency order
+type ModuleStoppable interface {
+	OnModuleStop()
+}
+
+/* Implementation */
+
+func isPrivateComponent(c *ComponentImpl) bool {
+	return c.Tags().Get("module") == "private"
+}
+
+//Private components are visible to components of the same module only
+func isVisibleTo(c *ComponentImpl, module string) bool {
+	return !isPrivateComponent(c) || c.module == module
+}
+
+//Returns module that owns components declared by def
+func declaredModule(def interface{}, parent string) string {
+	if m, ok := asModule(def); ok {
+		return m.ModuleName()
+	}
+	return parent
+}
+
+//Struct that embeds a module is not a module itself,
+//even though ModuleName is promoted to it
+func asModule(v interface{}) (Module, bool) {
+	m, ok := v.(Module)
+	if !ok {
+		return nil, false
+	}
+
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return m, true
+	}
+
+	st := val.Elem()
+	for i := 0; i < st.NumField(); i++ {
+		if !st.Type().Field(i).Anonymous || !st.Field(i).CanAddr() || !st.Field(i).CanInterface() {
+			continue
+		}
+
+		if em, ok := st.Field(i).Addr().Interface().(Module); ok && em.ModuleName() == m.ModuleName() {
+			return nil, false
+		}
+	}
+
+	return m, true
+}
+
+//Finds components of type t visible to requester
+func (c *MutableContext) FindComponentsVisibleTo(t reflect.Type, requester Component) []*ComponentImpl {
+	return visibleComponents(c.components, t, requester)
+}
+
+func visibleComponents(comps []*ComponentImpl, t reflect.Type, requester Component) []*ComponentImpl {
+	module := ""
+	if requester != nil {
+		module = ComponentModule(requester)
+	}
+
+	r := make([]*ComponentImpl, 0)
+	for _, v := range comps {
+		if v.ty.AssignableTo(t) && isVisibleTo(v, module) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+//Returns declared modules in dependency order
+func sortModules(comps []*ComponentImpl) ([]Module, error) {
+	byName := make(map[string]Module)
+	declared := make([]Module, 0)
+
+	for _, comp := range comps {
+		m, ok := asModule(comp.inst)
+		if !ok {
+			continue
+		}
+		if _, dup := byName[m.ModuleName()]; dup {
+			return nil, fmt.Errorf("Module %v is declared twice", m.ModuleName())
+		}
+		byName[m.ModuleName()] = m
+		declared = append(declared, m)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	r := make([]Module, 0, len(declared))
+
+	var visit func(m Module) error
+	visit = func(m Module) error {
+		name := m.ModuleName()
+
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("Circular module dependency on %v", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+
+		if d, ok := m.(DependentModule); ok {
+			for _, dep := range d.ModuleDependencies() {
+				dm, ok := byName[dep]
+				if !ok {
+					return fmt.Errorf("Module %v requires module %v which is not declared", name, dep)
+				}
+				if err := visit(dm); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[name] = visited
+		r = append(r, m)
+		return nil
+	}
+
+	for _, m := range declared {
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+//Starts modules in order. If one fails, already started modules are stopped
+func startModules(modules []Module) error {
+	for i, m := range modules {
+		if v, ok := m.(ModuleStartable); ok {
+			log.Println("Starting module", m.ModuleName())
+			if err := v.OnModuleStart(); err != nil {
+				stopModules(modules[:i])
+				return fmt.Errorf("Module %v failed to start: %v", m.ModuleName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func stopModules(modules []Module) {
+	for i := len(modules) - 1; i >= 0; i-- {
+		if v, ok := modules[i].(ModuleStoppable); ok {
+			log.Println("Stopping module", modules[i].ModuleName())
+			v.OnModuleStop()
+		}
+	}
+}
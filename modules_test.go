@@ -0,0 +1,181 @@
+package wntr
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type StorageHelper struct {
+}
+
+type StorageService struct {
+	Helper *StorageHelper `inject:"t"`
+}
+
+var gModuleEvents []string
+
+type StorageModule struct {
+	Service StorageService
+	Helper  StorageHelper `module:"private"`
+}
+
+func (*StorageModule) ModuleName() string {
+	return "storage"
+}
+
+func (*StorageModule) OnModuleStart() error {
+	gModuleEvents = append(gModuleEvents, "storage started")
+	return nil
+}
+
+func (*StorageModule) OnModuleStop() {
+	gModuleEvents = append(gModuleEvents, "storage stopped")
+}
+
+type ReportsModule struct {
+	Storage *StorageService `inject:"t"`
+}
+
+func (*ReportsModule) ModuleName() string {
+	return "reports"
+}
+
+func (*ReportsModule) ModuleDependencies() []string {
+	return []string{"storage"}
+}
+
+func (*ReportsModule) OnModuleStart() error {
+	gModuleEvents = append(gModuleEvents, "reports started")
+	return nil
+}
+
+func (*ReportsModule) OnModuleStop() {
+	gModuleEvents = append(gModuleEvents, "reports stopped")
+}
+
+func TestModuleComposition(t *testing.T) {
+	gModuleEvents = nil
+
+	var app struct {
+		Reports ReportsModule
+		Storage StorageModule
+	}
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.Storage.Service.Helper != &app.Storage.Helper || app.Reports.Storage != &app.Storage.Service {
+		t.Fatal("Module components were not wired", app)
+	}
+
+	helpers := ctx.(*MutableContext).FindComponentsByType(reflect.TypeOf(&app.Storage.Helper))
+	if len(helpers) != 0 {
+		t.Fatal("Private component leaked into global lookup", helpers)
+	}
+
+	ctx.Stop()
+
+	expected := []string{"storage started", "reports started", "reports stopped", "storage stopped"}
+	if !reflect.DeepEqual(gModuleEvents, expected) {
+		t.Fatal("Bad module hooks order", gModuleEvents)
+	}
+}
+
+func TestPrivateComponentIsNotInjectable(t *testing.T) {
+	var app struct {
+		Storage StorageModule
+		Outside StorageService
+	}
+
+	if _, err := FastBoot(&app); err == nil {
+		t.Fatal("Private component was injected outside of module")
+	}
+}
+
+func TestMissingModuleDependency(t *testing.T) {
+	var app struct {
+		Reports ReportsModule
+		Service StorageService
+		Helper  StorageHelper
+	}
+
+	if _, err := FastBoot(&app); err == nil {
+		t.Fatal("Missing module dependency was not detected")
+	} else {
+		t.Log("Ok:", err)
+	}
+}
+
+type FailingModule struct {
+}
+
+func (*FailingModule) ModuleName() string {
+	return "failing"
+}
+
+func (*FailingModule) ModuleDependencies() []string {
+	return []string{"reports"}
+}
+
+func (*FailingModule) OnModuleStart() error {
+	return fmt.Errorf("Port is busy")
+}
+
+func TestFailedModuleStopsStartedOnes(t *testing.T) {
+	gModuleEvents = nil
+
+	var app struct {
+		Failing FailingModule
+		Reports ReportsModule
+		Storage StorageModule
+	}
+
+	if _, err := FastBoot(&app); err == nil {
+		t.Fatal("Module failure was not reported")
+	}
+
+	expected := []string{"storage started", "reports started", "reports stopped", "storage stopped"}
+	if !reflect.DeepEqual(gModuleEvents, expected) {
+		t.Fatal("Started modules were not stopped", gModuleEvents)
+	}
+}
+
+type FailingComponent struct {
+}
+
+func (*FailingComponent) PostInit() error {
+	return fmt.Errorf("Connection refused")
+}
+
+func TestFailedStartDestroysConfiguredComponents(t *testing.T) {
+	var app struct {
+		Dao     CountingDao
+		Failing FailingComponent
+	}
+
+	if _, err := FastBoot(&app); err == nil {
+		t.Fatal("Component failure was not reported")
+	}
+	if app.Dao.destroyed != 1 {
+		t.Fatal("Configured component was not destroyed after failed start", app.Dao.destroyed)
+	}
+
+	gModuleEvents = nil
+
+	var failed struct {
+		Dao     CountingDao
+		Failing FailingModule
+		Reports ReportsModule
+		Storage StorageModule
+	}
+
+	if _, err := FastBoot(&failed); err == nil {
+		t.Fatal("Module failure was not reported")
+	}
+	if failed.Dao.destroyed != 1 {
+		t.Fatal("Configured component was not destroyed after failed module start", failed.Dao.destroyed)
+	}
+}
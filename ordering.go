@@ -0,0 +1,41 @@
+package wntr
+
+import (
+	"reflect"
+	"sort"
+)
+
+//Optional interface for components applied in chains,
+//like lifecycle processors, interceptors or injection wrappers
+//
+//  Components with lower order are applied first
+//  Components without explicit order have order 0
+type Ordered interface {
+	Order() int
+}
+
+//Sorts slice of components by Ordered, e.g. injected with `inject:"all"`
+//
+//  Equal ones keep their order. Panics if argument is not a slice
+func SortByOrder(slice interface{}) {
+	v := reflect.ValueOf(slice)
+	sort.SliceStable(slice, func(i, j int) bool {
+		return componentOrder(v.Index(i).Interface()) < componentOrder(v.Index(j).Interface())
+	})
+}
+
+/* Implementation */
+
+func componentOrder(v interface{}) int {
+	if o, ok := v.(Ordered); ok {
+		return o.Order()
+	}
+	return 0
+}
+
+//Sorts components by order, preserving registration order for equal ones
+func sortByOrder(comps []Component) {
+	sort.SliceStable(comps, func(i, j int) bool {
+		return componentOrder(comps[i].Instance()) < componentOrder(comps[j].Instance())
+	})
+}
@@ -0,0 +1,159 @@
+package wntr
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+)
+
+//Public contract for contexts that allow replacing
+//declared components before start
+//
+//  Mostly used by tests to swap real components with mocks
+//
+//   ctx, _ := wntr.CreateComplexContext(&app)
+//   ctx.(wntr.OverridableContext).OverrideByName("Dao", &MockDao{})
+//   ctx.Start()
+type OverridableContext interface {
+	Context
+	//Replaces component registered with given name
+	OverrideByName(name string, replacement interface{}) *ComponentOverride
+	//Replaces single component assignable to given type
+	OverrideByType(t reflect.Type, replacement interface{}) *ComponentOverride
+	//Returns all requested overrides
+	Overrides() []*ComponentOverride
+}
+
+//Single replacement request
+//
+//   Replacement takes place of original component on context start
+//   and keeps its name and tags
+type ComponentOverride struct {
+	name        string
+	ty          reflect.Type
+	replacement interface{}
+	original    interface{}
+	applied     bool
+}
+
+var _ OverridableContext = (*MutableContext)(nil)
+
+/* Implementation */
+
+func (c *MutableContext) OverrideByName(name string, replacement interface{}) *ComponentOverride {
+	o := &ComponentOverride{name: name, replacement: replacement}
+	c.overrides = append(c.overrides, o)
+	return o
+}
+
+func (c *MutableContext) OverrideByType(t reflect.Type, replacement interface{}) *ComponentOverride {
+	o := &ComponentOverride{ty: t, replacement: replacement}
+	c.overrides = append(c.overrides, o)
+	return o
+}
+
+func (c *MutableContext) Overrides() []*ComponentOverride {
+	return c.overrides
+}
+
+func (c *MutableContext) applyOverrides() error {
+	for _, o := range c.overrides {
+		if o.applied {
+			continue
+		}
+
+		targets := findOverrideTargets(c.components, o)
+
+		if len(targets) == 0 {
+			log.Println("Override", o, "did not match any component")
+			continue
+		}
+
+		if err := checkOverride(targets, o); err != nil {
+			return err
+		}
+
+		if err := c.replaceComponent(targets[0], o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findOverrideTargets(comps []*ComponentImpl, o *ComponentOverride) []*ComponentImpl {
+	r := make([]*ComponentImpl, 0)
+	for _, comp := range comps {
+		if o.ty != nil && comp.ty.AssignableTo(o.ty) {
+			r = append(r, comp)
+		} else if o.ty == nil && comp.name == o.name {
+			r = append(r, comp)
+		}
+	}
+	return r
+}
+
+//Checks that override may replace single matched component
+func checkOverride(targets []*ComponentImpl, o *ComponentOverride) error {
+	if len(targets) > 1 {
+		return fmt.Errorf("Ambiguous override %v: %v components matched", o, len(targets))
+	}
+
+	comp := targets[0]
+
+	if _, ok := comp.inst.(ComponentLifecycle); ok {
+		return fmt.Errorf("Cannot override %v: lifecycle processors cannot be replaced", comp.ty)
+	}
+	if _, ok := comp.inst.(CtxEventHandler); ok {
+		return fmt.Errorf("Cannot override %v: context event handlers cannot be replaced", comp.ty)
+	}
+
+	t := reflect.TypeOf(o.replacement)
+	if o.ty != nil && !t.AssignableTo(o.ty) {
+		return fmt.Errorf("Cannot override %v: %v is not assignable to it", o.ty, t)
+	}
+
+	return nil
+}
+
+func (c *MutableContext) replaceComponent(comp *ComponentImpl, o *ComponentOverride) error {
+	t := reflect.TypeOf(o.replacement)
+	log.Println("Overriding component", comp.ty, "with", t)
+
+	o.original = comp.inst
+	comp.inst = o.replacement
+	comp.ty = t
+
+	if v, ok := o.replacement.(ContextAware); ok {
+		if err := v.SetContext(c); err != nil {
+			return err
+		}
+	}
+
+	for _, handler := range c.registrationHandlers {
+		handler.OnComponentRegistered(comp)
+	}
+
+	o.applied = true
+	return nil
+}
+
+//Whether override has replaced a component
+func (o *ComponentOverride) Applied() bool {
+	return o.applied
+}
+
+//Replaced component instance, nil if override was not applied
+func (o *ComponentOverride) Original() interface{} {
+	return o.original
+}
+
+func (o *ComponentOverride) Replacement() interface{} {
+	return o.replacement
+}
+
+func (o *ComponentOverride) String() string {
+	if o.ty != nil {
+		return fmt.Sprintf("type %v -> %T", o.ty, o.replacement)
+	}
+	return fmt.Sprintf("name %v -> %T", o.name, o.replacement)
+}
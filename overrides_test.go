@@ -0,0 +1,77 @@
+package wntr
+
+import (
+	"reflect"
+	"testing"
+)
+
+type MockDao struct {
+	calls int
+}
+
+func (d *MockDao) DoJob() {
+	d.calls++
+}
+
+func TestOverrideByName(t *testing.T) {
+	var app struct {
+		Dao  RealDao `name:"dao" @mvc:"tagged"`
+		Ctrl Controller
+	}
+
+	ctx, err := CreateComplexContext(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &MockDao{}
+	octx := ctx.(OverridableContext)
+	applied := octx.OverrideByName("dao", mock)
+	missed := octx.OverrideByName("Dao", &MockDao{})
+
+	if err := ctx.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if !applied.Applied() || applied.Original() != &app.Dao {
+		t.Fatal("Override was not applied", applied)
+	}
+
+	if missed.Applied() {
+		t.Fatal("Override by field name must not match explicitly named component", missed)
+	}
+
+	if app.Ctrl.Dao != mock {
+		t.Fatal("Mock was not injected", app.Ctrl.Dao)
+	}
+
+	comps := ctx.(*MutableContext).FindComponentsByType(reflect.TypeOf(mock))
+	if len(comps) != 1 || comps[0].Tags().Get("@mvc") != "tagged" {
+		t.Fatal("Original tags were not kept", comps)
+	}
+}
+
+func TestOverrideByType(t *testing.T) {
+	var app struct {
+		Dao  RealDao
+		Ctrl Controller
+	}
+
+	ctx, err := CreateComplexContext(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &MockDao{}
+	o := ctx.(OverridableContext).OverrideByType(reflect.TypeOf((*Dao2)(nil)).Elem(), mock)
+
+	if err := ctx.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	if !o.Applied() || app.Ctrl.Dao != mock {
+		t.Fatal("Override by type was not applied", o)
+	}
+}
@@ -0,0 +1,111 @@
+package wntr
+
+import (
+	"reflect"
+	"strings"
+)
+
+//Public contract for configuration property holders
+//
+//  Properties are addressed by dotted path, like "database.url"
+//  Values are either scalars, slices or nested sections
+//  represented as map[string]interface{}
+type PropertySource interface {
+	Property(name string) (interface{}, bool)
+}
+
+//PropertySource that has to load its properties before use
+//
+//  LoadProperties may be called several times and must be idempotent
+type LoadablePropertySource interface {
+	PropertySource
+	LoadProperties() error
+}
+
+//Simple PropertySource backed by a tree of maps
+type MapPropertySource map[string]interface{}
+
+var _ PropertySource = MapPropertySource(nil)
+
+var gPropertySourceType reflect.Type = reflect.TypeOf((*PropertySource)(nil)).Elem()
+
+func (this MapPropertySource) Property(name string) (interface{}, bool) {
+	var cur interface{} = map[string]interface{}(this)
+
+	if name == "" {
+		return cur, true
+	}
+
+	for _, key := range strings.Split(name, ".") {
+		section, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if cur, ok = section[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+//Merges properties of every source into single tree. Later sources win
+func MergePropertySources(sources ...PropertySource) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+
+	for _, src := range sources {
+		if v, ok := src.(LoadablePropertySource); ok {
+			if err := v.LoadProperties(); err != nil {
+				return nil, err
+			}
+		}
+
+		if tree, ok := src.Property(""); ok {
+			if section, ok := tree.(map[string]interface{}); ok {
+				mergeProperties(r, copyProperties(section))
+			}
+		}
+	}
+
+	return r, nil
+}
+
+//Deep copy of property tree, so merging does not modify sources
+func copyProperties(src map[string]interface{}) map[string]interface{} {
+	r := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		r[k] = copyProperty(v)
+	}
+	return r
+}
+
+func copyProperty(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return copyProperties(t)
+	case []interface{}:
+		r := make([]interface{}, len(t))
+		for i, e := range t {
+			r[i] = copyProperty(e)
+		}
+		return r
+	}
+	return v
+}
+
+//Deep merges src properties over dst
+//  Nested sections are merged key by key, any other value is replaced
+func mergeProperties(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcSection, srcOk := v.(map[string]interface{})
+		dstSection, dstOk := dst[k].(map[string]interface{})
+
+		if srcOk && dstOk {
+			mergeProperties(dstSection, srcSection)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
@@ -0,0 +1,274 @@
+package wntr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+//Task run by Scheduler
+//
+//   Schedule is taken from `interval:"10s"` or `cron:"*/5 * * * *"` tag
+//   of component, or from Schedule method if task implements ScheduleAware.
+//   Func components of type `func(context.Context) error` or `func() error`
+//   declared with one of these tags are scheduled too.
+//   Context passed to task is cancelled when scheduler is stopped
+//
+//   var app struct {
+//           Scheduler wntr.Scheduler
+//           Cleanup   CleanupTask                     `interval:"1m"`
+//           Report    func(ctx context.Context) error `cron:"0 9 * * 1-5"`
+//   }
+type Scheduled interface {
+	RunScheduled(ctx context.Context) error
+}
+
+//Task that defines its own schedule
+type ScheduleAware interface {
+	Schedule() Schedule
+}
+
+//Component notified about failed task runs
+type TaskErrorHandler interface {
+	OnTaskError(task string, err error)
+}
+
+//State of scheduled task
+type TaskStatus struct {
+	Name      string
+	Runs      int
+	Failures  int
+	LastRun   time.Time
+	LastError error
+}
+
+//Runs scheduled tasks
+//
+//   Tasks are started on PostInit and stopped on PreDestroy.
+//   Run of a task never overlaps with the previous one: next run time is
+//   calculated after previous run is finished, missed runs are skipped.
+//   Returned errors and panics are logged and passed to TaskErrorHandler
+//   components. PreDestroy cancels context of running tasks and waits
+//   StopTimeout for them to finish. Tasks of ancestor contexts are scheduled too
+type Scheduler struct {
+	//Time to wait for running tasks on stop. Default is 30 seconds
+	StopTimeout time.Duration
+
+	//Tasks are injected, so they are destroyed after scheduler is stopped
+	Tasks         []Scheduled        `inject:"all"`
+	ErrorHandlers []TaskErrorHandler `inject:"all"`
+	Ctx           ConfiguredContext  `inject:"t"`
+
+	lock   sync.Mutex
+	tasks  []*scheduledTask
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func _() {
+	var _ PostInitable = &Scheduler{}
+	var _ PreDestroyable = &Scheduler{}
+}
+
+func (this *Scheduler) PostInit() error {
+	tasks, err := this.findTasks()
+	if err != nil {
+		return err
+	}
+
+	this.tasks = tasks
+
+	ctx, cancel := context.WithCancel(context.Background())
+	this.cancel = cancel
+
+	for _, t := range this.tasks {
+		log.Println("Scheduler: Scheduling task", t.status.Name)
+		this.wg.Add(1)
+		go this.loop(ctx, t)
+	}
+
+	return nil
+}
+
+func (this *Scheduler) PreDestroy() {
+	if this.cancel == nil {
+		return
+	}
+
+	log.Println("Scheduler: Stopping", len(this.tasks), "tasks")
+	this.cancel()
+	this.cancel = nil
+
+	done := make(chan struct{})
+	go func() {
+		this.wg.Wait()
+		close(done)
+	}()
+
+	timeout := this.StopTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Println("Scheduler: Tasks did not finish in", timeout)
+	}
+}
+
+//Returns state of every scheduled task
+func (this *Scheduler) Status() []TaskStatus {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	r := make([]TaskStatus, len(this.tasks))
+	for i, t := range this.tasks {
+		r[i] = t.status
+	}
+	return r
+}
+
+/* Implementation */
+
+type scheduledTask struct {
+	run      func(ctx context.Context) error
+	schedule Schedule
+	status   TaskStatus
+}
+
+//Injected tasks are matched with components to read their tags
+func (this *Scheduler) findTasks() ([]*scheduledTask, error) {
+	injected := make(map[Scheduled]bool, len(this.Tasks))
+	for _, t := range this.Tasks {
+		injected[t] = true
+	}
+
+	var comps []Component
+	if v, ok := this.Ctx.(HierarchicalConfiguredContext); ok {
+		comps = v.FindAllComponentsVisibleTo(gAnyType, nil)
+	} else {
+		comps = this.Ctx.FindComponentsByType(gAnyType)
+	}
+
+	r := make([]*scheduledTask, 0)
+	for _, c := range comps {
+		var run func(ctx context.Context) error
+
+		if v, ok := c.Instance().(Scheduled); ok {
+			if !injected[v] {
+				continue
+			}
+			delete(injected, v) //Scheduled once, even if visible twice
+			run = v.RunScheduled
+		} else if v, ok := c.Instance().(*func(context.Context) error); ok && *v != nil {
+			run = *v
+		} else if v, ok := c.Instance().(*func() error); ok && *v != nil {
+			f := *v
+			run = func(context.Context) error { return f() }
+		} else {
+			continue
+		}
+
+		schedule, err := taskSchedule(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if schedule == nil {
+			if _, ok := c.Instance().(Scheduled); ok {
+				return nil, fmt.Errorf("Task %v has no schedule. Declare `interval` or `cron` tag", taskName(c))
+			}
+			continue //Untagged funcs are not tasks
+		}
+
+		r = append(r, &scheduledTask{run: run, schedule: schedule, status: TaskStatus{Name: taskName(c)}})
+	}
+
+	return r, nil
+}
+
+func taskSchedule(c Component) (Schedule, error) {
+	if v, ok := c.Tags().Lookup("interval"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("Bad interval '%v' of task %v", v, taskName(c))
+		}
+		return Every(d), nil
+	}
+
+	if v, ok := c.Tags().Lookup("cron"); ok {
+		s, err := ParseCron(v)
+		if err != nil {
+			return nil, fmt.Errorf("Task %v: %v", taskName(c), err)
+		}
+		return s, nil
+	}
+
+	if v, ok := c.Instance().(ScheduleAware); ok {
+		return v.Schedule(), nil
+	}
+
+	return nil, nil
+}
+
+func taskName(c Component) string {
+	if name := ComponentName(c); name != "" {
+		return name
+	}
+	return c.Type().String()
+}
+
+func (this *Scheduler) loop(ctx context.Context, t *scheduledTask) {
+	defer this.wg.Done()
+
+	for {
+		next := t.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Println("Scheduler: Task", t.status.Name, "will never run again")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		this.runTask(ctx, t)
+	}
+}
+
+func (this *Scheduler) runTask(ctx context.Context, t *scheduledTask) {
+	start := time.Now()
+	err := runRecovered(ctx, t.run)
+
+	this.lock.Lock()
+	t.status.Runs++
+	t.status.LastRun = start
+	t.status.LastError = err
+	if err != nil {
+		t.status.Failures++
+	}
+	this.lock.Unlock()
+
+	if err != nil {
+		log.Println("Scheduler: Task", t.status.Name, "failed:", err)
+		for _, h := range this.ErrorHandlers {
+			h.OnTaskError(t.status.Name, err)
+		}
+	}
+}
+
+func runRecovered(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Task panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
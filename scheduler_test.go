@@ -0,0 +1,166 @@
+package wntr
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//Reports every run and waits until it is received
+type SignalTask struct {
+	running int32
+	overlap int32
+	runs    chan struct{}
+}
+
+func (t *SignalTask) RunScheduled(ctx context.Context) error {
+	if atomic.AddInt32(&t.running, 1) > 1 {
+		atomic.StoreInt32(&t.overlap, 1)
+	}
+	defer atomic.AddInt32(&t.running, -1)
+
+	select {
+	case t.runs <- struct{}{}:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+type FailureCollector struct {
+	failures chan string
+}
+
+func (c *FailureCollector) OnTaskError(task string, err error) {
+	select {
+	case c.failures <- task:
+	default:
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	var app struct {
+		Scheduler Scheduler
+		Signal    SignalTask   `interval:"1ns"`
+		Failing   func() error `interval:"1ns"`
+		Errors    FailureCollector
+	}
+	app.Signal.runs = make(chan struct{})
+	app.Failing = func() error {
+		panic(errors.New("boom"))
+	}
+	app.Errors.failures = make(chan string, 1)
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		<-app.Signal.runs
+	}
+	if task := <-app.Errors.failures; task != "Failing" {
+		t.Fatal("Bad failed task", task)
+	}
+
+	//Blocked run is cancelled, so stop does not wait for timeout
+	ctx.Stop()
+
+	if atomic.LoadInt32(&app.Signal.running) != 0 {
+		t.Fatal("Task is running after scheduler stopped")
+	}
+	if atomic.LoadInt32(&app.Signal.overlap) != 0 {
+		t.Fatal("Runs overlapped")
+	}
+}
+
+//Ignores cancellation
+type StuckTask struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *StuckTask) RunScheduled(context.Context) error {
+	close(t.started)
+	<-t.release
+	return nil
+}
+
+func TestSchedulerStopTimeout(t *testing.T) {
+	var app struct {
+		Scheduler Scheduler
+		Stuck     StuckTask `interval:"1ns"`
+	}
+	app.Scheduler.StopTimeout = time.Millisecond
+	app.Stuck.started = make(chan struct{})
+	app.Stuck.release = make(chan struct{})
+	defer close(app.Stuck.release)
+
+	ctx, err := FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-app.Stuck.started
+	ctx.Stop() //Returns after timeout
+}
+
+func TestSchedulerRunsParentTasks(t *testing.T) {
+	var root struct {
+		Signal SignalTask `interval:"1ns"`
+	}
+	var child struct {
+		Scheduler Scheduler
+	}
+	root.Signal.runs = make(chan struct{})
+
+	rootCtx := ContextOrPanic(&root)
+	defer rootCtx.Stop()
+
+	childCtx := forkOrFail(t, rootCtx, &child)
+	<-root.Signal.runs
+	childCtx.Stop()
+
+	if s := child.Scheduler.Status(); len(s) != 1 || s[0].Name != "Signal" {
+		t.Fatal("Task of parent context was not scheduled", s)
+	}
+}
+
+func TestSchedulerRequiresSchedule(t *testing.T) {
+	var app struct {
+		Scheduler Scheduler
+		Signal    SignalTask
+	}
+
+	if _, err := FastBoot(&app); err == nil {
+		t.Fatal("Task without schedule was accepted")
+	}
+}
+
+func TestCronSchedule(t *testing.T) {
+	cases := []struct {
+		expr, from, next string
+	}{
+		{"*/15 * * * *", "2024-01-01 10:07", "2024-01-01 10:15"},
+		{"0 9 * * 1-5", "2024-01-05 09:00", "2024-01-08 09:00"},
+		{"30 2 1 * *", "2024-01-15 00:00", "2024-02-01 02:30"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+	}
+
+	for _, c := range cases {
+		s, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		from, _ := time.Parse("2006-01-02 15:04", c.from)
+		if next := s.Next(from).Format("2006-01-02 15:04"); next != c.next {
+			t.Fatal("Bad next run of", c.expr, next, "expected", c.next)
+		}
+	}
+
+	if _, err := ParseCron("61 * * * *"); err == nil {
+		t.Fatal("Bad expression was accepted")
+	}
+}
@@ -0,0 +1,102 @@
+package wntr
+
+import (
+	"log"
+	"reflect"
+)
+
+//Context built by code generated with wntrgen
+//
+//   Generated code registers components, wires them with plain
+//   assignments and drives two-phase initialization by calling
+//   Prepare and Ready in the same order StandardLifecycle would.
+//
+//   It implements ConfiguredContext, so components like
+//   webmvc.RequestDispatcher can still look up others by type
+type StaticContext struct {
+	components []*ComponentImpl
+	//Initialized components in acquisition order
+	ready []interface{}
+	//Started modules in dependency order
+	modules []Module
+}
+
+var _ ConfiguredContext = (*StaticContext)(nil)
+
+func NewStaticContext() *StaticContext {
+	return &StaticContext{}
+}
+
+/* Implementation */
+
+//Registers wired component
+func (c *StaticContext) Register(name string, value interface{}, tags string) {
+	c.components = append(c.components, &ComponentImpl{value, reflect.TypeOf(value), tags, name, ""})
+}
+
+//1st initialization phase, calls PreInit
+func (c *StaticContext) Prepare(value interface{}) error {
+	if v, ok := value.(PreInitable); ok {
+		return v.PreInit()
+	}
+	return nil
+}
+
+//2nd initialization phase, calls PostInit and remembers component for Stop
+func (c *StaticContext) Ready(value interface{}) error {
+	if v, ok := value.(PostInitable); ok {
+		if err := v.PostInit(); err != nil {
+			return err
+		}
+	}
+	c.ready = append(c.ready, value)
+	return nil
+}
+
+//Runs module start hooks in dependency order
+func (c *StaticContext) StartModules() error {
+	modules, err := sortModules(c.components)
+	if err != nil {
+		return err
+	}
+
+	if err := startModules(modules); err != nil {
+		return err
+	}
+	c.modules = modules
+	return nil
+}
+
+//Stops initialized components and returns passed error
+//
+//  Used by generated code when initialization fails halfway
+func (c *StaticContext) Abort(err error) error {
+	c.Stop()
+	return err
+}
+
+//Calls PreDestroy in reverse initialization order
+func (c *StaticContext) Stop() error {
+	stopModules(c.modules)
+	c.modules = nil
+
+	for i := len(c.ready) - 1; i >= 0; i-- {
+		if v, ok := c.ready[i].(PreDestroyable); ok {
+			v.PreDestroy()
+		}
+	}
+	c.ready = nil
+
+	log.Println("Static context stopped")
+	return nil
+}
+
+func (c *StaticContext) FindComponentsByType(t reflect.Type) []Component {
+	r := make([]Component, 0)
+	for _, comp := range c.components {
+		if comp.ty.AssignableTo(t) && !isPrivateComponent(comp) {
+			r = append(r, comp)
+		}
+	}
+	return r
+}
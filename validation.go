@@ -0,0 +1,232 @@
+package wntr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//Aggregated result of context validation
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Context validation failed with %v problems:\n\t%v",
+		len(e.Problems), strings.Join(e.Problems, "\n\t"))
+}
+
+//Creates context from definitions and validates it without starting
+func ValidateDefinitions(definitions ...interface{}) error {
+	ctx, err := CreateComplexContext(definitions...)
+	if err != nil {
+		return err
+	}
+
+	return ctx.(*MutableContext).Validate()
+}
+
+//Checks wiring of context without starting it
+//
+//   Every `inject` tag is resolved against components that would be
+//   registered on start, including conditional components and overrides.
+//   Cardinality of `inject:"type"` fields is checked and dependency
+//   cycles are detected.
+//
+//   Candidates are looked up the same way as on start, so components of
+//   parent context are merged into `inject:"all"` and shadowed by name.
+//
+//   No lifecycle callbacks are invoked and no component is modified,
+//   property conditions read configuration files without keeping them.
+//   Returns *ValidationError with every problem found
+func (c *MutableContext) Validate() error {
+	comps, problems := c.plannedComponents()
+
+	v := &wiringValidator{
+		lookup:   &componentLookup{comps: comps},
+		edges:    make(map[*ComponentImpl][]*ComponentImpl),
+		problems: problems,
+	}
+
+	for _, comp := range comps {
+		if f, ok := comp.inst.(*ForkedLifecycle); ok {
+			v.lookup.parent = f.Parent
+		}
+	}
+
+	for _, comp := range comps {
+		v.checkComponent(comp)
+	}
+
+	v.checkCycles()
+
+	if len(v.problems) > 0 {
+		return &ValidationError{v.problems}
+	}
+	return nil
+}
+
+/* Implementation */
+
+//Returns copies of components that would be registered on start
+func (c *MutableContext) plannedComponents() ([]*ComponentImpl, []string) {
+	problems := make([]string, 0)
+
+	comps := make([]*ComponentImpl, len(c.components))
+	for i, comp := range c.components {
+		cp := *comp
+		comps[i] = &cp
+	}
+
+	known := knownTypes(c.components, c.conditional)
+
+	for _, cc := range c.conditional {
+		ok, err := c.checkConditions(cc, comps, known, false)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Unable to check conditions of %T: %v", cc.inst, err))
+			continue
+		}
+		if ok {
+			comps = append(comps, &ComponentImpl{cc.inst, reflect.TypeOf(cc.inst), cc.tags, cc.name, cc.module})
+		}
+	}
+
+	for _, o := range c.overrides {
+		targets := findOverrideTargets(comps, o)
+		if o.applied || len(targets) == 0 {
+			continue
+		}
+
+		if err := checkOverride(targets, o); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		targets[0].inst = o.replacement
+		targets[0].ty = reflect.TypeOf(o.replacement)
+	}
+
+	return comps, problems
+}
+
+type wiringValidator struct {
+	lookup   *componentLookup
+	edges    map[*ComponentImpl][]*ComponentImpl
+	problems []string
+}
+
+func (v *wiringValidator) report(comp *ComponentImpl, f reflect.StructField, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	v.problems = append(v.problems, fmt.Sprintf("%v.%v: %v", comp.ty, f.Name, msg))
+}
+
+//Returns candidates of this context, which are configured before requester
+func (v *wiringValidator) local(candidates []Component) []*ComponentImpl {
+	r := make([]*ComponentImpl, 0)
+	for _, c := range candidates {
+		for _, comp := range v.lookup.comps {
+			if c == Component(comp) {
+				r = append(r, comp)
+			}
+		}
+	}
+	return r
+}
+
+//Mirrors AutowiringProcessor.autowireInstance
+func (v *wiringValidator) checkComponent(comp *ComponentImpl) {
+	val := reflect.ValueOf(comp.inst)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return
+	}
+
+	ty := val.Elem().Type()
+
+	for i := 0; i < ty.NumField(); i++ {
+		f := ty.Field(i)
+
+		mode, ok := f.Tag.Lookup("inject")
+		if !ok {
+			continue
+		}
+
+		switch mode {
+		case "type", "t":
+			candidates := v.lookup.visibleTo(f.Type, comp)
+			if n := len(candidates); n == 0 {
+				v.report(comp, f, "Component not found. Type %v", f.Type)
+			} else if n > 1 {
+				v.report(comp, f, "Too many components with type %v. Expected 1, Got: %v", f.Type, n)
+			}
+			v.edges[comp] = append(v.edges[comp], v.local(candidates)...)
+
+		case "all", "a":
+			if f.Type.Kind() != reflect.Slice {
+				v.report(comp, f, "Bad inject:all field. Slice expected, got: %v", f.Type.Kind())
+				continue
+			}
+			candidates := v.lookup.allVisibleTo(f.Type.Elem(), comp)
+			v.edges[comp] = append(v.edges[comp], v.local(candidates)...)
+
+		default:
+			v.report(comp, f, "Unknown inject mode '%v'", mode)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			v.report(comp, f, "Field cannot be set. Is it declared public?")
+		}
+	}
+}
+
+func (v *wiringValidator) checkCycles() {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[*ComponentImpl]int)
+	path := make([]*ComponentImpl, 0)
+
+	var visit func(c *ComponentImpl)
+	visit = func(c *ComponentImpl) {
+		state[c] = visiting
+		path = append(path, c)
+
+		for _, dep := range v.edges[c] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				v.reportCycle(path, dep)
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[c] = visited
+	}
+
+	for _, comp := range v.lookup.comps {
+		if state[comp] == unvisited {
+			visit(comp)
+		}
+	}
+}
+
+func (v *wiringValidator) reportCycle(path []*ComponentImpl, start *ComponentImpl) {
+	names := make([]string, 0)
+	found := false
+
+	for _, c := range path {
+		if c == start {
+			found = true
+		}
+		if found {
+			names = append(names, c.ty.String())
+		}
+	}
+	names = append(names, start.ty.String())
+
+	v.problems = append(v.problems, "Circular dependency: "+strings.Join(names, " -> "))
+}
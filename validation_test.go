@@ -0,0 +1,110 @@
+package wntr
+
+import (
+	"testing"
+)
+
+type BrokenWiring struct {
+	Missing *CrudService     `inject:"t"`
+	Many    Dao2             `inject:"t"`
+	Bad     Dao2             `inject:"all"`
+	Typo    *TwoPhaseService `inject:"tpye"`
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	var app struct {
+		D1     DaoImpl2
+		D2     DaoImpl2
+		Svc    TwoPhaseService
+		Broken BrokenWiring
+		A      ClassA
+		B      ClassB
+		C      ClassC
+	}
+
+	ctx, err := CreateComplexContext(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.(*MutableContext).Validate()
+
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatal("Validation error expected, got", err)
+	}
+
+	//missing, ambiguous, bad slice, unknown mode and a cycle
+	if len(verr.Problems) != 5 {
+		t.Fatal("Bad problems reported", verr)
+	}
+
+	if app.Svc.phase1done || app.Svc.phase2done {
+		t.Fatal("Lifecycle was invoked during validation")
+	}
+
+	t.Log("Ok:", verr)
+}
+
+func TestValidateCorrectContext(t *testing.T) {
+	var app struct {
+		Baseapp
+		Dev  FakeDao `profile:"dev"`
+		Prof ActiveProfiles
+	}
+
+	if err := ValidateDefinitions(&app); err != nil {
+		t.Fatal(err)
+	}
+
+	//Fake dao would make controller's dependency ambiguous
+	app.Prof.Names = []string{"dev"}
+
+	if err := ValidateDefinitions(&app); err == nil {
+		t.Fatal("Ambiguous dependency of conditional component was not detected")
+	}
+}
+
+func TestValidateReadsPropertiesWithoutLoading(t *testing.T) {
+	var app struct {
+		Files ConfigFiles
+		Real  RealDao
+		Fake  FakeDao `on-property:"dao.fake"`
+		Ctrl  Controller
+	}
+	app.Files.Paths = []string{writeConfigFiles(t, map[string]string{"app.json": `{"dao": {"fake": true}}`})}
+
+	if err := ValidateDefinitions(&app); err == nil {
+		t.Fatal("Ambiguous dependency of component enabled by property was not detected")
+	}
+
+	if app.Files.props != nil {
+		t.Fatal("Validation loaded properties into component")
+	}
+}
+
+func TestValidateForkedContextLikeStart(t *testing.T) {
+	var root struct {
+		Dao HierarchyDao
+	}
+
+	rootCtx := ContextOrPanic(&root)
+	defer rootCtx.Stop()
+
+	var named struct {
+		Other    Dispatcher `name:"Dao"`
+		Consumer DaoConsumer
+	}
+
+	childCtx, err := ForkContext(rootCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PopulateContextFromDefinitions(childCtx, &named); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := childCtx.(*MutableContext).Validate(); err == nil {
+		t.Fatal("Parent component shadowed by name was considered")
+	}
+}
@@ -0,0 +1,136 @@
+package webmvc
+
+import (
+	"encoding/json"
+	"github.com/d-tar/wntr"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestAdminEndpoints(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Admin      AdminService
+		Endpoint   AdminEndpoint `@web-uri:"/admin/:section"`
+		Props      wntr.MapPropertySource
+	}
+	app.Props = wntr.MapPropertySource{
+		"db": map[string]interface{}{
+			"url":      "postgres://db",
+			"password": "qwerty",
+			"dsn":      "postgres://app:qwerty@db:5432/app",
+			"replicas": []interface{}{map[string]interface{}{"url": "postgres://r1", "password": "qwerty"}},
+		},
+	}
+
+	ctx, err := wntr.CreateComplexContext(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.RegisterComponent(app.Props)
+	if err := ctx.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	get := func(section string, v interface{}) {
+		w := httptest.NewRecorder()
+		app.Dispatcher.ServeHTTP(w, httptest.NewRequest("GET", "/admin/"+section, nil))
+		if w.Code != 200 {
+			t.Fatal("Bad status of", section, w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var config struct {
+		Db struct {
+			Url      string
+			Password string
+			Dsn      string
+			Replicas []map[string]string
+		}
+	}
+	get("config", &config)
+	db := config.Db
+	if db.Password != "******" || db.Url != "postgres://db" || db.Dsn != "postgres://app:******@db:5432/app" {
+		t.Fatal("Bad config", config)
+	}
+	if len(db.Replicas) != 1 || db.Replicas[0]["password"] != "******" || db.Replicas[0]["url"] != "postgres://r1" {
+		t.Fatal("Secrets of list elements were not masked", db.Replicas)
+	}
+
+	replica := app.Props["db"].(map[string]interface{})["replicas"].([]interface{})[0]
+	if replica.(map[string]interface{})["password"] != "qwerty" {
+		t.Fatal("Property source was modified", replica)
+	}
+
+	var deps map[string][]string
+	get("dependencies", &deps)
+	if !reflect.DeepEqual(deps["Endpoint"], []string{"Admin"}) {
+		t.Fatal("Bad dependencies", deps)
+	}
+
+	var routes []RouteInfo
+	get("routes", &routes)
+	if len(routes) != 1 || routes[0].Pattern != "/admin/:section" {
+		t.Fatal("Bad routes", routes)
+	}
+
+	var views []string
+	get("views", &views)
+	if !reflect.DeepEqual(views, []string{"", "JSON", "PROBLEM", "TEXT"}) {
+		t.Fatal("Bad views", views)
+	}
+}
+
+func TestAdminGuard(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Admin      AdminService
+		Guard      AdminGuard    `@filter-uri:"/admin/*"`
+		Endpoint   AdminEndpoint `@web-uri:"/admin/:section"`
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	status := func(remote string, token string) int {
+		r := httptest.NewRequest("GET", "/admin/routes", nil)
+		r.RemoteAddr = remote
+		r.Header.Set("X-Admin-Token", token)
+		w := httptest.NewRecorder()
+		app.Dispatcher.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if c := status("192.0.2.1:1234", ""); c != 403 {
+		t.Fatal("Remote request was not rejected", c)
+	}
+	if c := status("127.0.0.1:1234", ""); c != 200 {
+		t.Fatal("Loopback request was rejected", c)
+	}
+	if c := status("[::1]:1234", ""); c != 200 {
+		t.Fatal("IPv6 loopback request was rejected", c)
+	}
+
+	app.Guard.Allow = func(r *http.Request) bool {
+		return r.Header.Get("X-Admin-Token") == "secret"
+	}
+	if c := status("192.0.2.1:1234", "secret"); c != 200 {
+		t.Fatal("Allowed request was rejected", c)
+	}
+	if c := status("127.0.0.1:1234", ""); c != 403 {
+		t.Fatal("Request not approved by Allow was served", c)
+	}
+}
@@ -1,10 +1,11 @@
 package webmvc
 
 import (
-	"encoding/json"
+	"fmt"
 	"github.com/d-tar/wntr"
-	"io/ioutil"
 	"log"
+	"net/http"
+	"net/textproto"
 	"reflect"
 )
 
@@ -14,16 +15,44 @@ var _ WebController
 // In: *WebRequest
 // Out: WebResult
 
+//Wraps func(T) R or func(T) (R, error) into WebController
+//
+//   Fields of argument struct T are bound from request by tags:
+//
+//   type GetUser struct {
+//           Id      int       `@path-variable:"id"`
+//           Page    int       `@query-param:"page"`
+//           Tags    []string  `@query-param:"tag"`
+//           Trace   string    `@header:"X-Trace-Id"`
+//           Session string    `@cookie:"session"`
+//           Body    UserPatch `@request-body:"json"`
+//   }
+//
+//   Values are converted to field types by injected ConversionService,
+//   slice fields receive every value of query parameter or header.
+//   Body is decoded by WebViewResolver.Decoder of request Content-Type,
+//   unsupported media types are answered with 415.
+//   Missing values leave fields untouched. Conversion failures and bad
+//   request bodies are answered with 400 Bad Request.
+//
+//   Returned errors are converted to WebResult by ConversionService if it can,
+//   otherwise they are answered by ErrorHandler, see WebError.
+//
+//   Bound argument is then checked by RequestValidator according to
+//   `validate` tags, failed rules are answered with 400 listing field errors
 func AutoHandler(p interface{}) SmartWebHandler {
 	return SmartWebHandler{pFunc: reflect.ValueOf(p)}
 }
 
 type SmartWebHandler struct {
-	pFunc reflect.Value
-	Conv  wntr.ConversionService `inject:"t"`
+	pFunc     reflect.Value
+	Conv      wntr.ConversionService `inject:"t"`
+	Validator *RequestValidator      `inject:"t"`
+	Mvc       *WebViewResolver       `inject:"t"`
 }
 
 var _ WebController = (*SmartWebHandler)(nil)
+var _ wntr.PostInitable = (*SmartWebHandler)(nil)
 
 var WebResultType reflect.Type = reflect.TypeOf((*WebResult)(nil)).Elem()
 
@@ -37,18 +66,26 @@ func (p *SmartWebHandler) Serve(r *WebRequest) WebResult {
 	in := []reflect.Value{}
 
 	if tFunc.NumIn() != 0 {
-		in = p.setupMapping(tFunc, r)
+		var err error
+		if in, err = p.setupMapping(tFunc, r); err != nil {
+			return WebError(err)
+		}
+
+		if res := p.validate(in[0]); res != nil {
+			return res
+		}
 	}
 
 	out := p.pFunc.Call(in)
 
 	if tFunc.NumOut() == 2 { //if we can have error
 		if !out[1].IsNil() { //and error is not null
-			err := out[1].Interface().(error)
-			res, err := p.Conv.Convert(err, WebResultType)
+			handlerErr := out[1].Interface().(error)
 
+			//Errors without converter are answered by ErrorHandler
+			res, err := p.Conv.Convert(handlerErr, WebResultType)
 			if err != nil {
-				panic(err)
+				return WebError(handlerErr)
 			}
 
 			return res.(WebResult)
@@ -56,49 +93,183 @@ func (p *SmartWebHandler) Serve(r *WebRequest) WebResult {
 	}
 
 	value := out[0].Interface()
-	res, err := p.Conv.Convert(value, WebResultType)
+	if res, ok := value.(WebResult); ok {
+		return res
+	}
 
+	res, err := p.Conv.Convert(value, WebResultType)
 	if err != nil {
-		panic(err)
+		return WebError(fmt.Errorf("Cannot convert result of handler: %v", err))
 	}
 
 	return res.(WebResult)
 }
 
-func (p *SmartWebHandler) setupMapping(tFun reflect.Type, r *WebRequest) []reflect.Value {
+//Reports bad validation tags of argument at boot rather than on request
+func (p *SmartWebHandler) PostInit() error {
+	tFunc := p.pFunc.Type()
+	if tFunc.NumIn() == 0 {
+		return nil
+	}
+	return p.validator().CheckTags(tFunc.In(0))
+}
+
+func (p *SmartWebHandler) setupMapping(tFun reflect.Type, r *WebRequest) ([]reflect.Value, error) {
 	tIn := tFun.In(0) //We expect T here
 
 	in := reflect.New(tIn) // T -> allocate *T
 
-	p.processAnnotations(in.Elem(), r)
+	if err := p.processAnnotations(in.Elem(), r); err != nil {
+		return nil, err
+	}
+
+	return []reflect.Value{in.Elem()}, nil
+}
 
-	return []reflect.Value{in.Elem()}
+func (p *SmartWebHandler) resolver() *WebViewResolver {
+	if p.Mvc == nil {
+		return gDefaultResolver
+	}
+	return p.Mvc
 }
 
-func (p *SmartWebHandler) processAnnotations(v reflect.Value, r *WebRequest) {
+var gDefaultResolver = &WebViewResolver{}
+
+func (p *SmartWebHandler) validator() *RequestValidator {
+	if p.Validator == nil {
+		return gDefaultValidator
+	}
+	return p.Validator
+}
+
+func (p *SmartWebHandler) validate(in reflect.Value) WebResult {
+	if err := p.validator().Validate(in.Interface()); err != nil {
+		return WebError(err)
+	}
+	return nil
+}
+
+var gParamSources = []string{"@path-variable", "@query-param", "@header", "@cookie"}
+
+func (p *SmartWebHandler) processAnnotations(v reflect.Value, r *WebRequest) error {
 	ty := v.Type()
 	log.Println(ty)
 	for i := 0; i < ty.NumField(); i++ {
 		f := ty.Field(i)
 
-		if name := f.Tag.Get("@path-variable"); name != "" {
-			value := r.NamedParameters[name]
-			rv := reflect.ValueOf(value)
-			v.Field(i).Set(rv)
-		} else if name := f.Tag.Get("@request-body"); name != "" {
-			b, err := ioutil.ReadAll(r.HttpRequest.Body)
-			if err != nil {
-				panic(err)
+		if name := f.Tag.Get("@request-body"); name != "" {
+			contentType := r.HttpRequest.Header.Get("Content-Type")
+			dec := p.resolver().Decoder(contentType)
+			if dec == nil {
+				return NewStatusError(http.StatusUnsupportedMediaType, "Unsupported media type '%v'", contentType)
 			}
 
 			holder := reflect.New(f.Type)
-			err = json.Unmarshal(b, holder.Interface())
-			if err != nil {
-				panic(err)
+			if err := dec.Decode(r.HttpRequest, holder.Interface()); err != nil {
+				return BadRequest("Bad request body: %v", err)
 			}
 
 			v.Field(i).Set(holder.Elem())
+			continue
+		}
+
+		source, name, values := requestValues(f.Tag, r)
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := bindStrings(p.Conv, v.Field(i), values); err != nil {
+			return BadRequest("Bad %v '%v': %v", source[1:], name, err)
+		}
+	}
+
+	return nil
+}
+
+//Returns values of first parameter tag of field
+func requestValues(tag reflect.StructTag, r *WebRequest) (string, string, []string) {
+	for _, source := range gParamSources {
+		name := tag.Get(source)
+		if name == "" {
+			continue
+		}
+
+		switch source {
+		case "@path-variable":
+			if v, ok := r.NamedParameters[name]; ok {
+				return source, name, []string{v}
+			}
+		case "@query-param":
+			if r.Query == nil {
+				r.Query = r.HttpRequest.URL.Query()
+			}
+			return source, name, r.Query[name]
+		case "@header":
+			return source, name, r.HttpRequest.Header[textproto.CanonicalMIMEHeaderKey(name)]
+		case "@cookie":
+			if c, err := r.HttpRequest.Cookie(name); err == nil {
+				return source, name, []string{c.Value}
+			}
+		}
+		return source, name, nil
+	}
+
+	return "", "", nil
+}
+
+//Sets dst from string values, slices receive every value
+func bindStrings(conv wntr.ConversionService, dst reflect.Value, values []string) error {
+	ty := dst.Type()
+
+	if ty.Kind() == reflect.Slice && !gStringType.AssignableTo(ty) && !gStringType.ConvertibleTo(ty) {
+		r := reflect.MakeSlice(ty, len(values), len(values))
+		for i, s := range values {
+			v, err := convertString(conv, s, ty.Elem())
+			if err != nil {
+				return err
+			}
+			r.Index(i).Set(v)
 		}
+		dst.Set(r)
+		return nil
 	}
 
+	v, err := convertString(conv, values[0], ty)
+	if err != nil {
+		return err
+	}
+	dst.Set(v)
+	return nil
+}
+
+var gStringType reflect.Type = reflect.TypeOf("")
+
+func convertString(conv wntr.ConversionService, s string, ty reflect.Type) (reflect.Value, error) {
+	if gStringType.AssignableTo(ty) {
+		return reflect.ValueOf(s), nil
+	}
+	if ty.Kind() == reflect.String {
+		return reflect.ValueOf(s).Convert(ty), nil
+	}
+
+	if conv == nil {
+		return reflect.Value{}, fmt.Errorf("No ConversionService to convert string to %v", ty)
+	}
+
+	res, err := conv.Convert(s, ty)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if res == nil {
+		return reflect.Zero(ty), nil
+	}
+
+	rv := reflect.ValueOf(res)
+	if !rv.Type().AssignableTo(ty) {
+		if !rv.Type().ConvertibleTo(ty) {
+			return reflect.Value{}, fmt.Errorf("Converter returned %v instead of %v", rv.Type(), ty)
+		}
+		rv = rv.Convert(ty)
+	}
+	return rv, nil
 }
@@ -0,0 +1,89 @@
+package webmvc
+
+import (
+	"github.com/d-tar/wntr"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type bindingRequest struct {
+	Id      int      `@path-variable:"id"`
+	Name    string   `@path-variable:"name"`
+	Page    uint     `@query-param:"page"`
+	Tags    []string `@query-param:"tag"`
+	Limits  []int    `@query-param:"limit"`
+	Trace   string   `@header:"x-trace-id"`
+	Session string   `@cookie:"session"`
+}
+
+func newSmartHandler(t *testing.T, f interface{}) *SmartWebHandler {
+	conv := &wntr.GenericConversionService{
+		Converters: []wntr.Converter{
+			wntr.ConverterBridge(func(r *GenericWebResult) (WebResult, error) { return r, nil }),
+		},
+	}
+	if err := conv.PreInit(); err != nil {
+		t.Fatal(err)
+	}
+
+	h := AutoHandler(f)
+	h.Conv = conv
+	return &h
+}
+
+func serveSmart(h *SmartWebHandler, uri string, params map[string]string) WebResult {
+	r := httptest.NewRequest("GET", uri, nil)
+	r.Header.Set("X-Trace-Id", "trace-1")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	return h.Serve(&WebRequest{HttpRequest: r, NamedParameters: params, Query: r.URL.Query()})
+}
+
+func TestTypedParameterBinding(t *testing.T) {
+	var got bindingRequest
+	h := newSmartHandler(t, func(req bindingRequest) WebResult {
+		got = req
+		return WebOk("ok")
+	})
+
+	res := serveSmart(h, "/users/42?page=3&tag=a&tag=b&limit=1&limit=2", map[string]string{"id": "42", "name": "bob"})
+	if res.HttpCode() != 200 {
+		t.Fatal("Unexpected result", res.HttpCode(), res.Model())
+	}
+
+	expected := bindingRequest{
+		Id:      42,
+		Name:    "bob",
+		Page:    3,
+		Tags:    []string{"a", "b"},
+		Limits:  []int{1, 2},
+		Trace:   "trace-1",
+		Session: "s1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Bad binding %+v", got)
+	}
+}
+
+func TestParameterConversionFailure(t *testing.T) {
+	called := false
+	h := newSmartHandler(t, func(req bindingRequest) WebResult {
+		called = true
+		return WebOk("ok")
+	})
+
+	for _, uri := range []string{"/users/x", "/users/1?page=-1", "/users/1?limit=1&limit=z"} {
+		id := strings.TrimPrefix(strings.SplitN(uri, "?", 2)[0], "/users/")
+		res := serveSmart(h, uri, map[string]string{"id": id})
+		if res.HttpCode() != http.StatusBadRequest {
+			t.Fatal("Expected 400 for", uri, "got", res.HttpCode())
+		}
+		t.Log(res.Model())
+	}
+
+	if called {
+		t.Fatal("Handler must not be invoked on conversion failure")
+	}
+}
@@ -0,0 +1,346 @@
+package webmvc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/d-tar/wntr"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+//Decodes request bodies of one media type
+//
+//   Declare decoders and encoders as components to add media types or
+//   replace built-in ones, see WebViewResolver
+type BodyDecoder interface {
+	MediaType() string
+	Decode(r *http.Request, v interface{}) error
+}
+
+//Encodes models of one media type
+type BodyEncoder interface {
+	MediaType() string
+	Encode(w io.Writer, v interface{}) error
+}
+
+//Decoder and encoder of the same media type
+type Codec interface {
+	BodyDecoder
+	BodyEncoder
+}
+
+func _() {
+	var _ Codec = &JsonCodec{}
+	var _ Codec = &XmlCodec{}
+	var _ Codec = &FormCodec{}
+	var _ BodyDecoder = &MultipartDecoder{}
+	var _ Codec = &BinaryCodec{}
+}
+
+//Built-in decoders
+func DefaultDecoders() []BodyDecoder {
+	return defaultDecoders(nil)
+}
+
+//Built-in encoders in order of preference
+func DefaultEncoders() []BodyEncoder {
+	return defaultEncoders(nil)
+}
+
+func defaultDecoders(conv wntr.ConversionService) []BodyDecoder {
+	return []BodyDecoder{&JsonCodec{}, &XmlCodec{}, &FormCodec{Conv: conv}, &MultipartDecoder{Conv: conv}, &BinaryCodec{}}
+}
+
+func defaultEncoders(conv wntr.ConversionService) []BodyEncoder {
+	return []BodyEncoder{&JsonCodec{}, &XmlCodec{}, &FormCodec{Conv: conv}, &BinaryCodec{}}
+}
+
+//application/json codec
+type JsonCodec struct{}
+
+func (*JsonCodec) MediaType() string {
+	return "application/json"
+}
+
+func (*JsonCodec) Decode(r *http.Request, v interface{}) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (*JsonCodec) Encode(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+//application/xml codec
+type XmlCodec struct{}
+
+func (*XmlCodec) MediaType() string {
+	return "application/xml"
+}
+
+func (*XmlCodec) Decode(r *http.Request, v interface{}) error {
+	return xml.NewDecoder(r.Body).Decode(v)
+}
+
+func (*XmlCodec) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+//application/x-www-form-urlencoded codec
+//
+//   Decodes into url.Values, map[string]string or struct. Struct fields are
+//   named by `form` tag, or by field name, and converted by ConversionService.
+//   Encodes the same types, other field values are printed with fmt
+type FormCodec struct {
+	//String converters are used if not set
+	Conv wntr.ConversionService
+}
+
+func (*FormCodec) MediaType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+func (this *FormCodec) Decode(r *http.Request, v interface{}) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	return decodeForm(this.Conv, r.PostForm, nil, v)
+}
+
+func (*FormCodec) Encode(w io.Writer, v interface{}) error {
+	values, err := encodeForm(v)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, values.Encode())
+	return err
+}
+
+//multipart/form-data decoder
+//
+//   Decodes like FormCodec, fields of type *multipart.FileHeader
+//   and []*multipart.FileHeader receive uploaded files
+type MultipartDecoder struct {
+	//Part of form kept in memory, rest is stored in temporary files. 32MB by default
+	MaxMemory int64
+	Conv      wntr.ConversionService
+}
+
+func (*MultipartDecoder) MediaType() string {
+	return "multipart/form-data"
+}
+
+func (this *MultipartDecoder) Decode(r *http.Request, v interface{}) error {
+	max := this.MaxMemory
+	if max <= 0 {
+		max = 32 << 20
+	}
+
+	if err := r.ParseMultipartForm(max); err != nil {
+		return err
+	}
+	return decodeForm(this.Conv, r.MultipartForm.Value, r.MultipartForm.File, v)
+}
+
+//Binary codec, application/octet-stream by default
+//
+//   Decodes into *[]byte, encoding.BinaryUnmarshaler or protobuf-style
+//   Unmarshal([]byte) error. Encodes []byte, io.Reader,
+//   encoding.BinaryMarshaler or protobuf-style Marshal() ([]byte, error)
+//
+//   var app struct {
+//           Proto webmvc.BinaryCodec `config:"proto"` //type: application/x-protobuf
+//   }
+type BinaryCodec struct {
+	Type string
+}
+
+type protoUnmarshaler interface {
+	Unmarshal([]byte) error
+}
+
+type protoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+func (this *BinaryCodec) MediaType() string {
+	if this.Type == "" {
+		return "application/octet-stream"
+	}
+	return this.Type
+}
+
+func (this *BinaryCodec) Decode(r *http.Request, v interface{}) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case *[]byte:
+		*t = b
+		return nil
+	case encoding.BinaryUnmarshaler:
+		return t.UnmarshalBinary(b)
+	case protoUnmarshaler:
+		return t.Unmarshal(b)
+	}
+	return fmt.Errorf("Cannot decode %v into %T", this.MediaType(), v)
+}
+
+func (this *BinaryCodec) Encode(w io.Writer, v interface{}) error {
+	var b []byte
+	var err error
+
+	switch t := v.(type) {
+	case []byte:
+		b = t
+	case io.Reader:
+		_, err = io.Copy(w, t)
+		return err
+	case encoding.BinaryMarshaler:
+		b, err = t.MarshalBinary()
+	case protoMarshaler:
+		b, err = t.Marshal()
+	default:
+		return fmt.Errorf("Cannot encode %T as %v", v, this.MediaType())
+	}
+
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+/* Implementation */
+
+var gStringConversion = &wntr.GenericConversionService{StandardConverters: wntr.StringConverters()}
+var gFileHeaderType reflect.Type = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+func decodeForm(conv wntr.ConversionService, values url.Values, files map[string][]*multipart.FileHeader, v interface{}) error {
+	if conv == nil {
+		conv = gStringConversion
+	}
+
+	switch t := v.(type) {
+	case *url.Values:
+		*t = values
+		return nil
+	case *map[string]string:
+		*t = make(map[string]string)
+		for k := range values {
+			(*t)[k] = values.Get(k)
+		}
+		return nil
+	}
+
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Cannot decode form into %T", v)
+	}
+	dst = dst.Elem()
+
+	for i := 0; i < dst.NumField(); i++ {
+		f := dst.Type().Field(i)
+		name := formFieldName(f)
+		if f.PkgPath != "" || name == "" {
+			continue
+		}
+
+		switch {
+		case f.Type == gFileHeaderType:
+			if fh := files[name]; len(fh) > 0 {
+				dst.Field(i).Set(reflect.ValueOf(fh[0]))
+			}
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem() == gFileHeaderType:
+			if fh := files[name]; len(fh) > 0 {
+				dst.Field(i).Set(reflect.ValueOf(fh))
+			}
+		case len(values[name]) > 0:
+			if err := bindStrings(conv, dst.Field(i), values[name]); err != nil {
+				return fmt.Errorf("Bad form field '%v': %v", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func encodeForm(v interface{}) (url.Values, error) {
+	switch t := v.(type) {
+	case url.Values:
+		return t, nil
+	case map[string][]string:
+		return url.Values(t), nil
+	case map[string]string:
+		r := make(url.Values)
+		for k, s := range t {
+			r.Set(k, s)
+		}
+		return r, nil
+	}
+
+	src := reflect.Indirect(reflect.ValueOf(v))
+	if src.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Cannot encode %T as form", v)
+	}
+
+	r := make(url.Values)
+	for i := 0; i < src.NumField(); i++ {
+		f := src.Type().Field(i)
+		name := formFieldName(f)
+		if f.PkgPath != "" || name == "" {
+			continue
+		}
+
+		fv := src.Field(i)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				r.Add(name, fmt.Sprint(fv.Index(j).Interface()))
+			}
+			continue
+		}
+		r.Set(name, fmt.Sprint(fv.Interface()))
+	}
+	return r, nil
+}
+
+func formFieldName(f reflect.StructField) string {
+	tag := strings.Split(f.Tag.Get("form"), ",")[0]
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return tag
+}
+
+//Encodes whole body before response is started, so encoding errors can still be reported
+func encodeBody(enc BodyEncoder, v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := enc.Encode(&b, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
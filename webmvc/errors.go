@@ -0,0 +1,217 @@
+package webmvc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/d-tar/wntr"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+//Error answered with HTTP status
+//
+//   Return it from handlers, possibly wrapped, to choose response status
+//
+//   func findUser(req GetUser) (*User, error) {
+//           ...
+//           return nil, webmvc.NotFound("User %v not found", req.Id)
+//   }
+type StatusError struct {
+	Status int
+	Detail string
+	//Cause, not reported to client
+	Err error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v: %v", e.Detail, e.Err)
+	}
+	return e.Detail
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+func NewStatusError(status int, format string, args ...interface{}) error {
+	return &StatusError{Status: status, Detail: fmt.Sprintf(format, args...)}
+}
+
+func BadRequest(format string, args ...interface{}) error {
+	return NewStatusError(http.StatusBadRequest, format, args...)
+}
+
+func Unauthorized(format string, args ...interface{}) error {
+	return NewStatusError(http.StatusUnauthorized, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return NewStatusError(http.StatusForbidden, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return NewStatusError(http.StatusNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return NewStatusError(http.StatusConflict, format, args...)
+}
+
+//Problem details of failed request, RFC 7807
+type Problem struct {
+	Type     string      `json:"type,omitempty"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Errors   FieldErrors `json:"errors,omitempty"`
+}
+
+//Maps errors to problems
+//
+//   Declare mappers as components to customize responses to errors.
+//   Mapper returns nil for errors it does not handle.
+//   Mappers implementing wntr.Ordered are tried in order
+type ErrorMapper interface {
+	MapError(err error, r *WebRequest) *Problem
+}
+
+//Answers errors of request processing with problem+json responses
+//
+//   Handles errors returned with WebError, errors of rendering and panics.
+//   Custom ErrorMappers are tried first. Then StatusError is answered
+//   with its status, FieldErrors with 400 listing fields, and other errors
+//   with 500 without details, which are logged instead
+type ErrorHandler struct {
+	Mappers []ErrorMapper `inject:"all"`
+}
+
+var _ wntr.PostInitable = &ErrorHandler{}
+
+//Result of failed request, rendered by ErrorHandler
+func WebError(err error) WebResult {
+	return &errorResult{err}
+}
+
+//Sorts injected mappers by order
+func (this *ErrorHandler) PostInit() error {
+	wntr.SortByOrder(this.Mappers)
+	return nil
+}
+
+//Maps error to problem+json result
+func (this *ErrorHandler) Handle(err error, r *WebRequest) WebResult {
+	p := this.problem(err, r)
+
+	if p.Status >= 500 {
+		log.Println("ErrorHandler: Request failed:", err)
+	}
+
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" && r != nil && r.HttpRequest != nil {
+		p.Instance = r.HttpRequest.URL.Path
+	}
+
+	return &GenericWebResult{
+		data:     p,
+		httpCode: p.Status,
+		view:     "PROBLEM",
+	}
+}
+
+//Renders problems as application/problem+json
+func NewProblemView() WebView {
+	var q WebViewFunc = RenderProblemView
+	return q
+}
+
+func RenderProblemView(mav WebResult, w http.ResponseWriter, r *http.Request) error {
+	b, err := json.Marshal(mav.Model())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	if mav.HttpCode() != 0 {
+		w.WriteHeader(mav.HttpCode())
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+/* Implementation */
+
+var gDefaultErrorHandler = &ErrorHandler{}
+
+type errorResult struct {
+	err error
+}
+
+func (this *errorResult) ViewName() string {
+	return "PROBLEM"
+}
+
+//Error itself, RequestDispatcher renders it through its ErrorHandler
+func (this *errorResult) Model() interface{} {
+	return this.err
+}
+
+func (this *errorResult) HttpCode() int {
+	return errorStatus(this.err)
+}
+
+//Recovered panic of request processing
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("Panic: %v\n%s", e.value, e.stack)
+}
+
+func recoveredError(v interface{}) error {
+	return &panicError{v, debug.Stack()}
+}
+
+func (this *ErrorHandler) problem(err error, r *WebRequest) *Problem {
+	for _, m := range this.Mappers {
+		if p := m.MapError(err, r); p != nil {
+			if p.Status == 0 {
+				p.Status = http.StatusInternalServerError
+			}
+			return p
+		}
+	}
+
+	p := &Problem{Status: errorStatus(err)}
+
+	var se *StatusError
+	var fe FieldErrors
+	switch {
+	case errors.As(err, &se):
+		p.Detail = se.Detail
+	case errors.As(err, &fe):
+		p.Detail = "Validation failed"
+		p.Errors = fe
+	}
+	return p
+}
+
+func errorStatus(err error) int {
+	var se *StatusError
+	var fe FieldErrors
+	switch {
+	case errors.As(err, &se):
+		return se.Status
+	case errors.As(err, &fe):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,180 @@
+package webmvc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errQuotaExceeded = errors.New("quota exceeded")
+
+type quotaMapper struct{}
+
+func (quotaMapper) MapError(err error, r *WebRequest) *Problem {
+	if !errors.Is(err, errQuotaExceeded) {
+		return nil
+	}
+	return &Problem{Type: "https://example.com/quota", Status: http.StatusTooManyRequests, Detail: err.Error()}
+}
+
+type fallbackMapper struct{}
+
+func (fallbackMapper) Order() int {
+	return 10
+}
+
+func (fallbackMapper) MapError(err error, r *WebRequest) *Problem {
+	return &Problem{Status: http.StatusTeapot}
+}
+
+func newErrorDispatcher(t *testing.T, mappers ...ErrorMapper) *RequestDispatcher {
+	mvc := &WebViewResolver{}
+	if err := mvc.PreInit(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := &ErrorHandler{Mappers: mappers}
+	if err := errs.PostInit(); err != nil {
+		t.Fatal(err)
+	}
+
+	disp := &RequestDispatcher{Mvc: mvc, Errors: errs}
+
+	routes := map[string]HandlerFunc{
+		"/panic":        func(*WebRequest) WebResult { panic("boom") },
+		"/missing":      func(*WebRequest) WebResult { return WebError(NotFound("User %v not found", 42)) },
+		"/conflict":     func(*WebRequest) WebResult { return WebError(Conflict("Already exists")) },
+		"/invalid":      func(*WebRequest) WebResult { return WebError(FieldErrors{{"name", "required", "must not be empty"}}) },
+		"/quota":        func(*WebRequest) WebResult { return WebError(errQuotaExceeded) },
+		"/unrenderable": func(*WebRequest) WebResult { return WebOk(make(chan int)) },
+		"/abort":        func(*WebRequest) WebResult { panic(http.ErrAbortHandler) },
+	}
+
+	for p, h := range routes {
+		if err := disp.MapRequest(RequestMapping{Pattern: p, Handler: h}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, method := range []string{"PUT", "POST"} {
+		if err := disp.MapRequest(RequestMapping{Pattern: "/orders/:id", Method: method, Handler: namedController(method)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return disp
+}
+
+func serveProblem(t *testing.T, disp *RequestDispatcher, uri string) (int, *Problem) {
+	w := httptest.NewRecorder()
+	disp.ServeHTTP(w, httptest.NewRequest("GET", uri, nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatal("Bad content type of", uri, ct, w.Body)
+	}
+
+	p := &Problem{}
+	if err := json.Unmarshal(w.Body.Bytes(), p); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, p
+}
+
+func TestErrorResponses(t *testing.T) {
+	disp := newErrorDispatcher(t, quotaMapper{})
+
+	cases := []struct {
+		uri    string
+		status int
+		detail string
+	}{
+		{"/panic", 500, ""},
+		{"/unrenderable", 500, ""},
+		{"/missing", 404, "User 42 not found"},
+		{"/conflict", 409, "Already exists"},
+		{"/invalid", 400, "Validation failed"},
+		{"/quota", 429, "quota exceeded"},
+	}
+
+	for _, c := range cases {
+		code, p := serveProblem(t, disp, c.uri)
+		if code != c.status || p.Status != c.status || p.Detail != c.detail || p.Instance != c.uri || p.Title == "" {
+			t.Fatalf("Bad problem of %v: %v %+v", c.uri, code, p)
+		}
+	}
+
+	if _, p := serveProblem(t, disp, "/invalid"); len(p.Errors) != 1 || p.Errors[0].Field != "name" {
+		t.Fatal("Field errors were not reported", p.Errors)
+	}
+}
+
+func TestErrorMapperOrder(t *testing.T) {
+	disp := newErrorDispatcher(t, fallbackMapper{}, quotaMapper{})
+
+	if code, _ := serveProblem(t, disp, "/quota"); code != http.StatusTooManyRequests {
+		t.Fatal("Ordered mapper must be tried last, got", code)
+	}
+
+	if code, _ := serveProblem(t, disp, "/missing"); code != http.StatusTeapot {
+		t.Fatal("Custom mapper must precede built-in mapping, got", code)
+	}
+
+	if code, _ := serveProblem(t, disp, "/unmapped"); code != http.StatusTeapot {
+		t.Fatal("Unmapped path was not answered by ErrorHandler, got", code)
+	}
+}
+
+func TestUnmappedRequests(t *testing.T) {
+	disp := newErrorDispatcher(t)
+
+	w := httptest.NewRecorder()
+	disp.ServeHTTP(w, httptest.NewRequest("GET", "/users/%3Cscript%3Ealert(1)%3C/script%3E", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/problem+json" || strings.Contains(w.Body.String(), "<script>") {
+		t.Fatal("Bad response to unmapped path", w.Code, w.Header(), w.Body)
+	}
+
+	code, p := serveProblem(t, disp, "/orders/42")
+	if code != http.StatusMethodNotAllowed || p.Status != http.StatusMethodNotAllowed {
+		t.Fatalf("Bad response to unmapped method: %v %+v", code, p)
+	}
+
+	w = httptest.NewRecorder()
+	disp.ServeHTTP(w, httptest.NewRequest("DELETE", "/orders/42", nil))
+	if allow := w.Header().Get("Allow"); allow != "POST, PUT" {
+		t.Fatal("Bad allowed methods", allow)
+	}
+}
+
+func TestAbortHandlerPanicIsPropagated(t *testing.T) {
+	disp := newErrorDispatcher(t)
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatal("Abort panic was not propagated", v)
+		}
+	}()
+
+	disp.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+}
+
+func TestPanicAfterResponseStarted(t *testing.T) {
+	mvc := &WebViewResolver{}
+	mvc.SetWebViews(map[string]WebView{"": WebViewFunc(func(mav WebResult, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic("view failed")
+	})})
+
+	disp := &RequestDispatcher{Mvc: mvc}
+	if err := disp.MapRequest(RequestMapping{Pattern: "/", Handler: namedController("ok")}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	disp.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusAccepted || w.Body.String() != "partial" {
+		t.Fatal("Started response must be left intact", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,191 @@
+package webmvc
+
+import (
+	"fmt"
+	"github.com/d-tar/wntr"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//Wraps WebController of matched route
+//
+//   Filters are components found by RequestDispatcher. They apply to every
+//   route unless scoped by `@filter-uri:"/api/*,/admin/*"` tag or FilterScope,
+//   patterns use route syntax. Filter scopes are matched against route patterns,
+//   so chain of every route is built once, when it is mapped.
+//   Filter with lower wntr.Ordered order runs first and wraps the following ones
+//
+//   var filters struct {
+//           Auth    AuthFilter            `@filter-uri:"/api/*"`
+//           Cors    webmvc.MiddlewareFunc `@filter-uri:"/api/*"`
+//           Logging RequestLogger
+//   }
+type WebFilter interface {
+	Filter(next WebController) WebController
+}
+
+//WebFilter function serving request by itself or passing it to next controller
+//
+//   app.Auth = func(r *webmvc.WebRequest, next webmvc.WebController) webmvc.WebResult {
+//           if r.HttpRequest.Header.Get("Authorization") == "" {
+//                   return webmvc.WebError(webmvc.Unauthorized("Token required"))
+//           }
+//           return next.Serve(r)
+//   }
+type FilterFunc func(r *WebRequest, next WebController) WebResult
+
+//Wraps http.Handler serving requests, like WebFilter
+//
+//   Middlewares run before route is matched, so they serve requests
+//   of unmapped methods and paths within their scope too, e.g. CORS preflights.
+//   Their scopes are matched against request path. Request passed on
+//   by middlewares is routed, so they may rewrite its path
+type HttpMiddleware interface {
+	Middleware(next http.Handler) http.Handler
+}
+
+//HttpMiddleware function
+type MiddlewareFunc func(next http.Handler) http.Handler
+
+//Filter or middleware limited to request paths matching any of patterns
+type FilterScope interface {
+	FilterPatterns() []string
+}
+
+func _() {
+	var _ WebFilter = FilterFunc(nil)
+	var _ HttpMiddleware = MiddlewareFunc(nil)
+}
+
+func (this FilterFunc) Filter(next WebController) WebController {
+	return HandlerFunc(func(r *WebRequest) WebResult {
+		return this(r, next)
+	})
+}
+
+func (this MiddlewareFunc) Middleware(next http.Handler) http.Handler {
+	return this(next)
+}
+
+/* Implementation */
+
+type scopedFilter struct {
+	filter     WebFilter
+	middleware HttpMiddleware
+	scope      *routeNode //nil for every path
+}
+
+//Collects filters and middlewares, sorted by order
+func (disp *RequestDispatcher) setupFilters() error {
+	wntr.SortByOrder(disp.Filters)
+	wntr.SortByOrder(disp.Middlewares)
+
+	disp.filters = make([]*scopedFilter, 0)
+	for _, f := range disp.Filters {
+		sf, err := disp.scopeFilter(f)
+		if err != nil {
+			return err
+		}
+		sf.filter = f
+		disp.filters = append(disp.filters, sf)
+	}
+
+	disp.middlewares = make([]*scopedFilter, 0)
+	for _, m := range disp.Middlewares {
+		sf, err := disp.scopeFilter(m)
+		if err != nil {
+			return err
+		}
+		sf.middleware = m
+		disp.middlewares = append(disp.middlewares, sf)
+	}
+
+	return nil
+}
+
+func (disp *RequestDispatcher) scopeFilter(f interface{}) (*scopedFilter, error) {
+	var patterns []string
+	if s, ok := f.(FilterScope); ok {
+		patterns = s.FilterPatterns()
+	} else if v := disp.componentTags(f).Get("@filter-uri"); v != "" {
+		patterns = strings.Split(v, ",")
+	}
+
+	sf := &scopedFilter{}
+	if len(patterns) > 0 {
+		sf.scope = newRouteNode()
+		for _, p := range patterns {
+			segs, _, err := parseRoute(strings.TrimSpace(p))
+			if err != nil {
+				return nil, fmt.Errorf("Bad scope of filter %T: %v", f, err)
+			}
+			sf.scope.insert(segs, &RequestMapping{Pattern: p}, disp.CaseInsensitive) //Duplicates are harmless
+		}
+	}
+
+	return sf, nil
+}
+
+//Returns tags of component declaring instance
+func (disp *RequestDispatcher) componentTags(inst interface{}) reflect.StructTag {
+	if disp.Ctx == nil {
+		return ""
+	}
+
+	ty := reflect.TypeOf(inst)
+	if !ty.Comparable() {
+		return ""
+	}
+
+	for _, c := range disp.Ctx.FindComponentsByType(ty) {
+		if reflect.TypeOf(c.Instance()) == ty && c.Instance() == inst {
+			return c.Tags()
+		}
+	}
+	return ""
+}
+
+func (this *scopedFilter) matches(segs []string, fold bool) bool {
+	if this.scope == nil {
+		return true
+	}
+	m, _ := this.scope.match(segs, "", nil, fold)
+	return m != nil
+}
+
+//Returns handler wrapped with middlewares matching path, first one is outermost.
+//Chains are cached by set of matching middlewares
+func (disp *RequestDispatcher) middlewareChain(segs []string) http.Handler {
+	matched := make([]*scopedFilter, 0, len(disp.middlewares))
+	key := make([]byte, 0)
+	for i, f := range disp.middlewares {
+		if f.matches(segs, disp.CaseInsensitive) {
+			matched = append(matched, f)
+			key = strconv.AppendInt(append(key, ','), int64(i), 10)
+		}
+	}
+
+	if h, ok := disp.middlewareChains.Load(string(key)); ok {
+		return h.(http.Handler)
+	}
+
+	var h http.Handler = http.HandlerFunc(disp.serveRoute)
+	for i := len(matched) - 1; i >= 0; i-- {
+		h = matched[i].middleware.Middleware(h)
+	}
+
+	actual, _ := disp.middlewareChains.LoadOrStore(string(key), h)
+	return actual.(http.Handler)
+}
+
+//Wraps controller with filters matching route pattern, first one is outermost
+func (disp *RequestDispatcher) filterChain(c WebController, patternSegs []string) WebController {
+	for i := len(disp.filters) - 1; i >= 0; i-- {
+		if f := disp.filters[i]; f.matches(patternSegs, disp.CaseInsensitive) {
+			c = f.filter.Filter(c)
+		}
+	}
+	return c
+}
@@ -0,0 +1,280 @@
+package webmvc
+
+import (
+	"bufio"
+	"github.com/d-tar/wntr"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//Appends its name to trace of request
+type traceFilter struct {
+	name  string
+	trace *[]string
+	order int
+}
+
+func (this *traceFilter) Order() int {
+	return this.order
+}
+
+func (this *traceFilter) Filter(next WebController) WebController {
+	return HandlerFunc(func(r *WebRequest) WebResult {
+		*this.trace = append(*this.trace, this.name)
+		return next.Serve(r)
+	})
+}
+
+//Answers 401 to requests without Authorization header
+type authFilter func(r *WebRequest, next WebController) WebResult
+
+func (this authFilter) Filter(next WebController) WebController {
+	return FilterFunc(this).Filter(next)
+}
+
+func (authFilter) Order() int {
+	return 1
+}
+
+type scopedTraceFilter struct {
+	traceFilter
+}
+
+func (this *scopedTraceFilter) FilterPatterns() []string {
+	return []string{"/admin/*"}
+}
+
+func (this *scopedTraceFilter) Order() int {
+	return -1
+}
+
+func TestFilterChain(t *testing.T) {
+	trace := make([]string, 0)
+
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+
+		Logging traceFilter
+		Auth    authFilter `@filter-uri:"/api/*"`
+		Admin   scopedTraceFilter
+		Cors    MiddlewareFunc `@filter-uri:"/api/*"`
+
+		Users  HandlerFunc `@web-uri:"/api/users" @web-method:"GET"`
+		Status HandlerFunc `@web-uri:"/status"`
+		Panel  HandlerFunc `@web-uri:"/admin/panel"`
+	}
+
+	ok := func(*WebRequest) WebResult { return WebOk("ok") }
+	app.Users, app.Status, app.Panel = ok, ok, ok
+
+	app.Logging = traceFilter{"logging", &trace, 2}
+	app.Admin = scopedTraceFilter{traceFilter{"admin", &trace, 0}}
+	app.Auth = func(r *WebRequest, next WebController) WebResult {
+		trace = append(trace, "auth")
+		if r.HttpRequest.Header.Get("Authorization") == "" {
+			return WebError(Unauthorized("Token required"))
+		}
+		return next.Serve(r)
+	}
+	app.Cors = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	cases := []struct {
+		method, uri, auth string
+		code              int
+		trace             string
+		cors              bool
+	}{
+		{"GET", "/api/users", "token", 200, "auth,logging", true},
+		{"GET", "/api/users", "", 401, "auth", true},
+		{"OPTIONS", "/api/users", "", 204, "", true},
+		{"GET", "/status", "", 200, "logging", false},
+		{"GET", "/admin/panel", "", 200, "admin,logging", false},
+	}
+
+	for _, c := range cases {
+		trace = trace[:0]
+
+		r := httptest.NewRequest(c.method, c.uri, nil)
+		if c.auth != "" {
+			r.Header.Set("Authorization", c.auth)
+		}
+		w := httptest.NewRecorder()
+		app.Dispatcher.ServeHTTP(w, r)
+
+		if w.Code != c.code || strings.Join(trace, ",") != c.trace || (w.Header().Get("Access-Control-Allow-Origin") != "") != c.cors {
+			t.Fatal("Bad response to", c.method, c.uri, w.Code, trace, w.Header())
+		}
+	}
+}
+
+func TestBadFilterScope(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Filter     FilterFunc `@filter-uri:"/api/:.json"`
+	}
+	app.Filter = func(r *WebRequest, next WebController) WebResult { return next.Serve(r) }
+
+	if ctx, err := wntr.FastBoot(&app); err == nil {
+		ctx.Stop()
+		t.Fatal("Bad scope pattern was accepted")
+	}
+}
+
+//Injects the only controller of context
+type controllerConsumer struct {
+	Home WebController `inject:"t"`
+}
+
+func TestFilterFuncIsNotDecorator(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Decorators wntr.DecoratorProcessor
+
+		Filter   FilterFunc  `@filter-uri:"/"`
+		Home     HandlerFunc `@web-uri:"/"`
+		Consumer controllerConsumer
+	}
+
+	app.Home = func(*WebRequest) WebResult { return WebOk("home") }
+	filtered := 0
+	app.Filter = func(r *WebRequest, next WebController) WebResult {
+		filtered++
+		return next.Serve(r)
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	app.Consumer.Home.Serve(&WebRequest{HttpRequest: httptest.NewRequest("GET", "/", nil)})
+	if filtered != 0 {
+		t.Fatal("Filter decorated injected controller")
+	}
+
+	app.Dispatcher.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if filtered != 1 {
+		t.Fatal("Filter was not applied to route", filtered)
+	}
+}
+
+//Counts chains it is applied to
+type countingFilter struct {
+	chains int
+}
+
+func (this *countingFilter) Filter(next WebController) WebController {
+	this.chains++
+	return next
+}
+
+func TestChainsAreBuiltOnce(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+
+		Counter countingFilter `@filter-uri:"/new/*"`
+		Rewrite MiddlewareFunc `@filter-uri:"/old/*"`
+
+		Item HandlerFunc `@web-uri:"/new/:id"`
+		Home HandlerFunc `@web-uri:"/"`
+	}
+
+	app.Item = func(r *WebRequest) WebResult { return WebOk(r.NamedParameters["id"]) }
+	app.Home = func(*WebRequest) WebResult { return WebOk("home") }
+
+	middlewares := 0
+	app.Rewrite = func(next http.Handler) http.Handler {
+		middlewares++
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.URL.Path = "/new/" + strings.TrimPrefix(r.URL.Path, "/old/")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		app.Dispatcher.ServeHTTP(w, httptest.NewRequest("GET", "/old/42", nil))
+		if w.Code != 200 || w.Body.String() != `"42"` {
+			t.Fatal("Rewritten request was not routed", w.Code, w.Body)
+		}
+	}
+
+	if app.Counter.chains != 1 || middlewares != 1 {
+		t.Fatal("Chains were built per request", app.Counter.chains, middlewares)
+	}
+}
+
+//Hijackable response writer
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (this *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	this.hijacked = true
+	return nil, nil, nil
+}
+
+func TestMiddlewareHijacksConnection(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+
+		Upgrade MiddlewareFunc `@filter-uri:"/ws"`
+	}
+
+	app.Upgrade = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+				t.Error("Hijack was not forwarded", err)
+			}
+		})
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	app.Dispatcher.ServeHTTP(w, httptest.NewRequest("GET", "/ws", nil))
+
+	if !w.hijacked {
+		t.Fatal("Connection was not hijacked")
+	}
+}
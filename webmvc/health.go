@@ -0,0 +1,83 @@
+package webmvc
+
+import (
+	"github.com/d-tar/wntr"
+	"net/http"
+)
+
+//Health endpoints module
+//
+//   Serves aggregated health of wntr.HealthIndicator components
+//
+//   GET /health            - status of every indicator
+//   GET /health/liveness   - whether process is able to serve requests
+//   GET /health/readiness  - whether context is started and no indicator is DOWN
+//
+//   Endpoints answer 200 for UP and DEGRADED statuses and 503 for DOWN
+//
+//   var app struct {
+//           webmvc.EnableDefaultWebMvc
+//           Health webmvc.EnableHealthEndpoints
+//   }
+type EnableHealthEndpoints struct {
+	Service   wntr.HealthService
+	Health    HealthEndpoint    `@web-uri:"/health" @web-method:"GET"`
+	Liveness  LivenessEndpoint  `@web-uri:"/health/liveness" @web-method:"GET"`
+	Readiness ReadinessEndpoint `@web-uri:"/health/readiness" @web-method:"GET"`
+}
+
+//Serves aggregated health
+type HealthEndpoint struct {
+	Service *wntr.HealthService `inject:"t"`
+}
+
+//Serves liveness probe
+type LivenessEndpoint struct {
+	Service *wntr.HealthService `inject:"t"`
+}
+
+//Serves readiness probe
+type ReadinessEndpoint struct {
+	Service *wntr.HealthService `inject:"t"`
+}
+
+func _() {
+	var _ wntr.DependentModule = &EnableHealthEndpoints{}
+	var _ WebController = &HealthEndpoint{}
+	var _ WebController = &LivenessEndpoint{}
+	var _ WebController = &ReadinessEndpoint{}
+}
+
+func (*EnableHealthEndpoints) ModuleName() string {
+	return "health"
+}
+
+func (*EnableHealthEndpoints) ModuleDependencies() []string {
+	return []string{"webmvc"}
+}
+
+func (this *HealthEndpoint) Serve(*WebRequest) WebResult {
+	return healthResult(this.Service.Check())
+}
+
+func (this *LivenessEndpoint) Serve(*WebRequest) WebResult {
+	return healthResult(this.Service.Liveness())
+}
+
+func (this *ReadinessEndpoint) Serve(*WebRequest) WebResult {
+	return healthResult(this.Service.Readiness())
+}
+
+/* Implementation */
+
+func healthResult(r wntr.HealthReport) WebResult {
+	code := http.StatusOK
+	if r.Status == wntr.HealthDown {
+		code = http.StatusServiceUnavailable
+	}
+
+	return &GenericWebResult{
+		data:     r,
+		httpCode: code,
+	}
+}
@@ -0,0 +1,103 @@
+package webmvc
+
+import (
+	"github.com/d-tar/wntr"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type failingIndicator struct {
+}
+
+func (*failingIndicator) HealthName() string {
+	return "db"
+}
+
+func (*failingIndicator) Health() wntr.Health {
+	return wntr.Health{Status: wntr.HealthDown}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Service    wntr.HealthService
+		Liveness   LivenessEndpoint  `@web-uri:"/health/liveness"`
+		Readiness  ReadinessEndpoint `@web-uri:"/health/readiness"`
+		Db         failingIndicator
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	expected := map[string]int{
+		"/health/liveness":  http.StatusOK,
+		"/health/readiness": http.StatusServiceUnavailable,
+	}
+
+	for uri, code := range expected {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", uri, nil)
+		app.Dispatcher.ServeHTTP(w, r)
+
+		if w.Code != code {
+			t.Fatal("Bad status of", uri, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestEnableHealthEndpoints(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var app struct {
+		EnableDefaultWebMvc
+		Health EnableHealthEndpoints
+		Db     failingIndicator
+	}
+	app.Web.Listener = ln
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := "http://" + app.Web.Addr().String()
+
+	resp, err := http.Get(base + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(body), `"db"`) {
+		t.Fatal("Bad health response", resp.StatusCode, string(body))
+	}
+
+	//Readiness is probed while context is stopping
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			resp, err := http.Get(base + "/health/readiness")
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+		}
+	}()
+
+	ctx.Stop()
+	<-done
+}
@@ -0,0 +1,40 @@
+package webmvc
+
+import (
+	"github.com/d-tar/wntr"
+)
+
+//Metrics endpoint module
+//
+//   Serves wntr.MetricsRegistry in Prometheus text format on GET /metrics
+//
+//   var app struct {
+//           webmvc.EnableDefaultWebMvc
+//           Metrics  wntr.EnableMetrics
+//           Endpoint webmvc.EnableMetricsEndpoint
+//   }
+type EnableMetricsEndpoint struct {
+	Metrics MetricsEndpoint `@web-uri:"/metrics" @web-method:"GET"`
+}
+
+//Serves metrics in Prometheus text format
+type MetricsEndpoint struct {
+	Registry *wntr.MetricsRegistry `inject:"t"`
+}
+
+func _() {
+	var _ wntr.DependentModule = &EnableMetricsEndpoint{}
+	var _ WebController = &MetricsEndpoint{}
+}
+
+func (*EnableMetricsEndpoint) ModuleName() string {
+	return "metrics-endpoint"
+}
+
+func (*EnableMetricsEndpoint) ModuleDependencies() []string {
+	return []string{"webmvc", "metrics"}
+}
+
+func (this *MetricsEndpoint) Serve(*WebRequest) WebResult {
+	return WebText(this.Registry)
+}
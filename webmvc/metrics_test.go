@@ -0,0 +1,78 @@
+package webmvc
+
+import (
+	"github.com/d-tar/wntr"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Metrics    wntr.EnableMetrics
+		Endpoint   MetricsEndpoint `@web-uri:"/metrics"`
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	for _, uri := range []string{"/missing", "/metrics"} {
+		app.Dispatcher.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", uri, nil))
+	}
+
+	w := httptest.NewRecorder()
+	app.Dispatcher.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	out := w.Body.String()
+	expected := []string{
+		`http_requests_total{method="GET",route="unmatched",code="404"} 1`,
+		`http_requests_total{method="GET",route="/metrics",code="200"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/metrics"} 1`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Fatal("Expected metric not found:", line, "\n", out)
+		}
+	}
+}
+
+func TestForkedDispatcherSharesMetrics(t *testing.T) {
+	var app, child struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+	}
+
+	var metrics struct {
+		Metrics wntr.EnableMetrics
+	}
+
+	ctx, err := wntr.FastBoot(&app, &metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	childCtx, err := wntr.ForkContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wntr.PopulateContextFromDefinitions(childCtx, &child); err != nil {
+		t.Fatal(err)
+	}
+	if err := childCtx.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer childCtx.Stop()
+
+	if child.Dispatcher.requests == nil || child.Dispatcher.requests != app.Dispatcher.requests {
+		t.Fatal("Child dispatcher does not share request metrics")
+	}
+}
@@ -0,0 +1,231 @@
+package webmvc
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//Returns decoder of media type from Content-Type header.
+//
+//   Decoder components take precedence over built-in ones of the same media type.
+//   Requests without Content-Type are decoded as JSON. Returns nil if media type is not supported
+func (this *WebViewResolver) Decoder(contentType string) BodyDecoder {
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	for _, d := range this.decoders() {
+		if strings.EqualFold(d.MediaType(), mt) {
+			return d
+		}
+	}
+	return nil
+}
+
+//Returns encoder of media type most preferred by Accept header.
+//
+//   Quality of media type is the one of the most specific range matching it,
+//   so 'application/json;q=0, */*' accepts anything but JSON.
+//   Encoder components take precedence over built-in ones, the first encoder
+//   is used when Accept is empty or accepts any type. Returns nil if no media type is acceptable
+func (this *WebViewResolver) Encoder(accept string) BodyEncoder {
+	if acceptable := this.acceptableEncoders(accept); len(acceptable) > 0 {
+		return acceptable[0]
+	}
+	return nil
+}
+
+//View encoding model with encoder negotiated by Accept header
+//
+//   It is the default view of WebViewResolver.
+//   If model can't be encoded, next acceptable encoder is tried,
+//   error of the first one is returned if none succeeds.
+//   Answers 406 Not Acceptable if no encoder matches
+func (this *WebViewResolver) NegotiatingView() WebView {
+	var q WebViewFunc = this.renderNegotiated
+	return q
+}
+
+/* Implementation */
+
+//Resolver without PostInit, e.g. not managed by context, merges codecs on every call
+func (this *WebViewResolver) decoders() []BodyDecoder {
+	if this.allDecoders != nil {
+		return this.allDecoders
+	}
+	return mergeDecoders(this.Decoders, DefaultDecoders())
+}
+
+func (this *WebViewResolver) encoders() []BodyEncoder {
+	if this.allEncoders != nil {
+		return this.allEncoders
+	}
+	return mergeEncoders(this.Encoders, DefaultEncoders())
+}
+
+func mergeDecoders(declared, defaults []BodyDecoder) []BodyDecoder {
+	r := append([]BodyDecoder{}, declared...)
+	seen := make(map[string]bool)
+	for _, d := range r {
+		seen[strings.ToLower(d.MediaType())] = true
+	}
+
+	for _, d := range defaults {
+		if !seen[d.MediaType()] {
+			r = append(r, d)
+		}
+	}
+	return r
+}
+
+func mergeEncoders(declared, defaults []BodyEncoder) []BodyEncoder {
+	r := append([]BodyEncoder{}, declared...)
+	seen := make(map[string]bool)
+	for _, e := range r {
+		seen[strings.ToLower(e.MediaType())] = true
+	}
+
+	for _, e := range defaults {
+		if !seen[e.MediaType()] {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+//Returns encoders of acceptable media types, most preferred first
+func (this *WebViewResolver) acceptableEncoders(accept string) []BodyEncoder {
+	encoders := this.encoders()
+
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return encoders
+	}
+
+	r := make([]BodyEncoder, 0)
+	quality := make([]float64, 0)
+	for _, e := range encoders {
+		q := mediaQuality(ranges, e.MediaType())
+		if q <= 0 {
+			continue
+		}
+
+		//Insertion keeps encoder order on equal quality
+		pos := len(r)
+		for pos > 0 && quality[pos-1] < q {
+			pos--
+		}
+		r = append(r[:pos], append([]BodyEncoder{e}, r[pos:]...)...)
+		quality = append(quality[:pos], append([]float64{q}, quality[pos:]...)...)
+	}
+	return r
+}
+
+func (this *WebViewResolver) renderNegotiated(mav WebResult, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Add("Vary", "Accept")
+
+	var encodeErr error
+	for _, enc := range this.acceptableEncoders(r.Header.Get("Accept")) {
+		b, err := encodeBody(enc, mav.Model())
+		if err != nil {
+			log.Printf("Cannot encode %T as %v: %v", mav.Model(), enc.MediaType(), err)
+			if encodeErr == nil {
+				encodeErr = err
+			}
+			continue
+		}
+
+		w.Header().Set("Content-Type", enc.MediaType())
+		if mav.HttpCode() != 0 {
+			w.WriteHeader(mav.HttpCode())
+		}
+
+		_, err = w.Write(b)
+		return err
+	}
+
+	//Model no acceptable encoder supports is server error
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	supported := make([]string, 0)
+	for _, e := range this.encoders() {
+		supported = append(supported, e.MediaType())
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNotAcceptable)
+	_, err := fmt.Fprint(w, "Not Acceptable. Supported media types: ", strings.Join(supported, ", "))
+	return err
+}
+
+//Media range of Accept header
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+//Returns media ranges of Accept header, including excluded ones with q=0
+func parseAccept(accept string) []mediaRange {
+	r := make([]mediaRange, 0)
+
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		r = append(r, mediaRange{mt, q})
+	}
+
+	return r
+}
+
+//Returns quality of the most specific range matching media type, 0 if none matches
+func mediaQuality(ranges []mediaRange, mediaType string) float64 {
+	q, specificity := 0.0, -1
+	for _, rng := range ranges {
+		if rng.matches(mediaType) && rng.specificity() > specificity {
+			q, specificity = rng.q, rng.specificity()
+		}
+	}
+	return q
+}
+
+func (m mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	switch {
+	case m.mediaType == "*/*":
+		return true
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*"))
+	}
+	return m.mediaType == mediaType
+}
@@ -0,0 +1,248 @@
+package webmvc
+
+import (
+	"bytes"
+	"github.com/d-tar/wntr"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type negotiatedModel struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestEncoderNegotiation(t *testing.T) {
+	mvc := &WebViewResolver{}
+
+	cases := map[string]string{
+		"":                               "application/json",
+		"*/*":                            "application/json",
+		"application/xml":                "application/xml",
+		"text/html, application/*;q=0.9": "application/json",
+		"application/json;q=0.5, application/xml":                "application/xml",
+		"application/*;q=0.8, application/x-www-form-urlencoded": "application/x-www-form-urlencoded",
+		"application/json;q=0, */*":                              "application/xml",
+		"*/*;q=0.5, application/xml;q=0.1":                       "application/json",
+	}
+
+	for accept, expected := range cases {
+		if e := mvc.Encoder(accept); e == nil || e.MediaType() != expected {
+			t.Fatal("Bad encoder for", accept, e)
+		}
+	}
+
+	if e := mvc.Encoder("text/html, application/json;q=0"); e != nil {
+		t.Fatal("Nothing is acceptable, got", e.MediaType())
+	}
+}
+
+func renderNegotiated(t *testing.T, mvc *WebViewResolver, accept string, model interface{}) *httptest.ResponseRecorder {
+	if err := mvc.PreInit(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", accept)
+
+	res, ok := model.(WebResult)
+	if !ok {
+		res = WebOk(model)
+	}
+
+	if err := mvc.HandleWebResult(res, w, r); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestNegotiatingView(t *testing.T) {
+	w := renderNegotiated(t, &WebViewResolver{}, "application/xml", &negotiatedModel{"bob"})
+	if w.Header().Get("Content-Type") != "application/xml" || !strings.Contains(w.Body.String(), "<name>bob</name>") {
+		t.Fatal("Bad XML response", w.Header(), w.Body)
+	}
+
+	w = renderNegotiated(t, &WebViewResolver{}, "", &negotiatedModel{"bob"})
+	if w.Body.String() != `{"name":"bob"}` {
+		t.Fatal("Bad JSON response", w.Body)
+	}
+
+	w = renderNegotiated(t, &WebViewResolver{}, "image/png", &negotiatedModel{"bob"})
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatal("Expected 406, got", w.Code)
+	}
+}
+
+//Replaces built-in JSON encoder
+type upperJsonEncoder struct{}
+
+func (upperJsonEncoder) MediaType() string {
+	return "application/json"
+}
+
+func (upperJsonEncoder) Encode(w io.Writer, v interface{}) error {
+	_, err := io.WriteString(w, strings.ToUpper(v.(*negotiatedModel).Name))
+	return err
+}
+
+func TestEncoderComponent(t *testing.T) {
+	mvc := &WebViewResolver{Encoders: []BodyEncoder{upperJsonEncoder{}}}
+
+	w := renderNegotiated(t, mvc, "application/json", &negotiatedModel{"bob"})
+	if w.Body.String() != "BOB" {
+		t.Fatal("Encoder component was not used", w.Body)
+	}
+}
+
+type formRequest struct {
+	Name  string   `form:"name"`
+	Age   int      `form:"age"`
+	Tags  []string `form:"tag"`
+	Token string   `form:"-"`
+}
+
+type uploadRequest struct {
+	Title string                `form:"title"`
+	File  *multipart.FileHeader `form:"file"`
+}
+
+type binaryMessage struct {
+	data []byte
+}
+
+func (m *binaryMessage) Unmarshal(b []byte) error {
+	m.data = b
+	return nil
+}
+
+func (m *binaryMessage) Marshal() ([]byte, error) {
+	return m.data, nil
+}
+
+func serveBody(h *SmartWebHandler, contentType string, body io.Reader) WebResult {
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", contentType)
+	return h.Serve(&WebRequest{HttpRequest: r, Query: r.URL.Query()})
+}
+
+func TestRequestBodyDecoding(t *testing.T) {
+	var form formRequest
+	h := newSmartHandler(t, func(req struct {
+		Body formRequest `@request-body:"form"`
+	}) WebResult {
+		form = req.Body
+		return WebOk("ok")
+	})
+
+	res := serveBody(h, "application/x-www-form-urlencoded", strings.NewReader("name=bob&age=42&tag=a&tag=b&Token=x"))
+	if res.HttpCode() != 200 || form.Name != "bob" || form.Age != 42 || len(form.Tags) != 2 || form.Token != "" {
+		t.Fatalf("Bad form decoding %v %+v", res.Model(), form)
+	}
+
+	if res := serveBody(h, "application/x-www-form-urlencoded", strings.NewReader("age=old")); res.HttpCode() != http.StatusBadRequest {
+		t.Fatal("Expected 400, got", res.HttpCode())
+	}
+
+	if res := serveBody(h, "text/csv", strings.NewReader("bob,42")); res.HttpCode() != http.StatusUnsupportedMediaType {
+		t.Fatal("Expected 415, got", res.HttpCode())
+	}
+}
+
+func TestMultipartDecoding(t *testing.T) {
+	var upload uploadRequest
+	h := newSmartHandler(t, func(req struct {
+		Body uploadRequest `@request-body:"multipart"`
+	}) WebResult {
+		upload = req.Body
+		return WebOk("ok")
+	})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "report")
+	fw, _ := mw.CreateFormFile("file", "report.txt")
+	fw.Write([]byte("content"))
+	mw.Close()
+
+	if res := serveBody(h, mw.FormDataContentType(), &body); res.HttpCode() != 200 {
+		t.Fatal("Unexpected result", res.HttpCode(), res.Model())
+	}
+
+	if upload.Title != "report" || upload.File == nil || upload.File.Filename != "report.txt" {
+		t.Fatalf("Bad multipart decoding %+v", upload)
+	}
+
+	f, _ := upload.File.Open()
+	if b, _ := ioutil.ReadAll(f); string(b) != "content" {
+		t.Fatal("Bad file content", string(b))
+	}
+}
+
+func TestBinaryCodec(t *testing.T) {
+	var msg binaryMessage
+	h := newSmartHandler(t, func(req struct {
+		Body binaryMessage `@request-body:"binary"`
+	}) WebResult {
+		msg = req.Body
+		return WebOk("ok")
+	})
+
+	if res := serveBody(h, "application/octet-stream", strings.NewReader("\x01\x02")); res.HttpCode() != 200 || string(msg.data) != "\x01\x02" {
+		t.Fatal("Bad binary decoding", res.Model(), msg.data)
+	}
+
+	proto := &BinaryCodec{Type: "application/x-protobuf"}
+	w := renderNegotiated(t, &WebViewResolver{Encoders: []BodyEncoder{proto}}, "application/x-protobuf", &msg)
+	if w.Header().Get("Content-Type") != "application/x-protobuf" || w.Body.String() != "\x01\x02" {
+		t.Fatal("Bad binary response", w.Header(), w.Body.Bytes())
+	}
+}
+
+func TestNegotiationFallsBackToEncodableType(t *testing.T) {
+	//XML can't encode maps
+	model := map[string]string{"name": "bob"}
+
+	w := renderNegotiated(t, &WebViewResolver{}, "application/xml, application/json;q=0.5", model)
+	if w.Header().Get("Content-Type") != "application/json" || w.Body.String() != `{"name":"bob"}` {
+		t.Fatal("Next acceptable encoder was not tried", w.Header(), w.Body)
+	}
+}
+
+type celsius float64
+
+func TestFormCodecUsesConversionService(t *testing.T) {
+	var app struct {
+		Mvc  WebViewResolver
+		Conv wntr.GenericConversionService
+	}
+
+	fromString := wntr.ConverterBridge(func(s string) (celsius, error) {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "C"), 64)
+		return celsius(v), err
+	})
+
+	ctx, err := wntr.FastDefaultContext(&app.Mvc, &app.Conv, fromString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	var v struct {
+		Temp celsius `form:"temp"`
+	}
+	r := httptest.NewRequest("POST", "/", strings.NewReader("temp=21.5C"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if err := app.Mvc.Decoder(r.Header.Get("Content-Type")).Decode(r, &v); err != nil || v.Temp != 21.5 {
+		t.Fatal("Declared ConversionService was not used", v, err)
+	}
+}
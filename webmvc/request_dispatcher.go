@@ -5,38 +5,78 @@ import (
 
 )
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/d-tar/wntr"
 	"log"
+	"net"
 	"net/http"
+	"path"
+	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 )
 
 type RequestMapping struct {
-	Method          string
-	Pattern         string
-	Handler         WebController
-	CompiledPattern *regexp.Regexp
-	NamedParams     []string
+	Method      string
+	Pattern     string
+	Handler     WebController
+	NamedParams []string
+
+	chain WebController //Handler wrapped with filters
 }
 
 var _ http.Handler = &RequestDispatcher{}
 
-type mappedPattern struct {
-	Method string
-	Path   string
-}
-
+//Routes requests to mapped WebControllers
+//
+//   Routes are matched by decoded path segments with deterministic precedence,
+//   see routeNode. Conflicting routes are rejected by MapRequest
+//
+//   Requests pass HttpMiddlewares and WebFilters before reaching controller.
+//   Panics and errors of handlers and views are answered by ErrorHandler,
+//   see WebError, as well as unmapped paths (404) and methods (405)
+//
+//   If wntr.MetricsRegistry is declared in context, dispatcher collects
+//   http_requests_total and http_request_duration_seconds metrics
+//   labeled by method, route pattern and status code
 type RequestDispatcher struct {
-	mappingTable map[mappedPattern]*RequestMapping
-
-	Mvc *WebViewResolver       `inject:"t"`
-	Ctx wntr.ConfiguredContext `inject:"t"`
+	//Redirect to path with or without trailing slash when only that one is mapped
+	RedirectTrailingSlash bool
+	//Match text of path segments ignoring case, parameter values keep their case.
+	//  Applies to routes mapped after it is set
+	CaseInsensitive bool
+
+	routes   *routeNode
+	mappings []*RequestMapping //in registration order
+
+	//Filters and middlewares, see WebFilter
+	Filters     []WebFilter      `inject:"all"`
+	Middlewares []HttpMiddleware `inject:"all"`
+
+	filters          []*scopedFilter //sorted by order
+	middlewares      []*scopedFilter //sorted by order
+	middlewareChains sync.Map        //by matched middlewares
+	requests         *wntr.Counter
+	durations        *wntr.Histogram
+
+	Mvc    *WebViewResolver       `inject:"t"`
+	Errors *ErrorHandler          `inject:"t"`
+	Ctx    wntr.ConfiguredContext `inject:"t"`
 }
 
 func (disp *RequestDispatcher) PostInit() error {
+	//Routes are filtered when mapped
+	if err := disp.setupFilters(); err != nil {
+		return err
+	}
+
 	for _, ctl := range disp.Ctx.FindComponentsByType(gWebControllerType) {
 
 		tag := ctl.Tags()
@@ -49,93 +89,313 @@ func (disp *RequestDispatcher) PostInit() error {
 			}
 
 			if err := disp.MapRequest(rmap); err != nil {
-				return nil
+				return err
 			}
 
 		}
 	}
+
+	for _, c := range disp.Ctx.FindComponentsByType(gMetricsRegistryType) {
+		if err := disp.setupMetrics(c.Instance().(*wntr.MetricsRegistry)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (disp *RequestDispatcher) MapRequest(m RequestMapping) error {
-	seq, params := ScanMappingPattern(m.Pattern)
-	rgx, err := CompileRegex(seq)
+func (disp *RequestDispatcher) setupMetrics(registry *wntr.MetricsRegistry) error {
+	var err error
+
+	disp.requests, err = registry.NewCounter("http_requests_total", "Served HTTP requests", "method", "route", "code")
 	if err != nil {
 		return err
 	}
-	m.CompiledPattern = rgx
-	m.NamedParams = params
 
-	k := mappedPattern{
-		Method: m.Method,
-		Path:   rgx.String(),
+	disp.durations, err = registry.NewHistogram("http_request_duration_seconds", "HTTP request latency", nil, "method", "route")
+	return err
+}
+
+func (disp *RequestDispatcher) MapRequest(m RequestMapping) error {
+	segs, params, err := parseRoute(m.Pattern)
+	if err != nil {
+		return err
 	}
 
-	if disp.mappingTable == nil {
-		disp.mappingTable = make(map[mappedPattern]*RequestMapping)
+	m.NamedParams = params
+	m.chain = disp.filterChain(m.Handler, segs)
+
+	if disp.routes == nil {
+		disp.routes = newRouteNode()
 	}
 
-	if _, ok := disp.mappingTable[k]; ok {
-		return fmt.Errorf("Pattern '%v' already mapped")
+	if err := disp.routes.insert(segs, &m, disp.CaseInsensitive); err != nil {
+		return err
 	}
 	log.Printf("RequestDispatcher: Mapped |%v| %v on to %v \n", m.Method, m.Pattern, m.Handler)
-	disp.mappingTable[k] = &m
+	disp.mappings = append(disp.mappings, &m)
 	return nil
 }
 
+//Returns mapped routes sorted by pattern and method
+func (disp *RequestDispatcher) Mappings() []RequestMapping {
+	r := make([]RequestMapping, 0, len(disp.mappings))
+	for _, m := range disp.mappings {
+		r = append(r, *m)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Pattern != r[j].Pattern {
+			return r[i].Pattern < r[j].Pattern
+		}
+		return r[i].Method < r[j].Method
+	})
+	return r
+}
+
 func (disp *RequestDispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	uri := r.RequestURI
+	st := &dispatchState{rec: &statusRecorder{ResponseWriter: w, code: http.StatusOK}, route: "unmatched"}
 
-	m, params := disp.findMappingForUri(uri, r.Method)
+	if disp.requests != nil {
+		defer func(start time.Time) {
+			disp.requests.Inc(r.Method, st.route, strconv.Itoa(st.rec.code))
+			disp.durations.Observe(time.Since(start).Seconds(), r.Method, st.route)
+		}(time.Now())
+	}
 
-	if m == nil {
-		disp.serve404(w, r)
-		return
+	defer func() {
+		if v := recover(); v != nil {
+			//Aborts response, handled by http.Server
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			webReq := st.webReq
+			if webReq == nil { //Panic of middleware
+				webReq = &WebRequest{HttpRequest: r, Query: r.URL.Query()}
+			}
+			disp.serveError(st.rec, st.rec, webReq, recoveredError(v))
+		}
+	}()
+
+	segs, _ := decodePath(r.URL.EscapedPath())
+	r = r.WithContext(context.WithValue(r.Context(), dispatchStateKey{}, st))
+
+	disp.middlewareChain(segs).ServeHTTP(st.rec, r)
+}
+
+//Request being served, shared by ServeHTTP and cached middleware chains
+type dispatchState struct {
+	rec    *statusRecorder
+	route  string //Metrics label
+	webReq *WebRequest
+}
+
+type dispatchStateKey struct{}
+
+//Routes request passed by middlewares
+func (disp *RequestDispatcher) serveRoute(w http.ResponseWriter, r *http.Request) {
+	st, ok := r.Context().Value(dispatchStateKey{}).(*dispatchState)
+	if !ok { //Middleware replaced request context
+		st = &dispatchState{rec: &statusRecorder{ResponseWriter: w, code: http.StatusOK}}
 	}
 
-	handler := m.Handler
+	m, params := disp.findMappingForUri(r.URL.EscapedPath(), r.Method)
+	if m != nil {
+		st.route = m.Pattern
+	}
 
-	webReq := &WebRequest{
+	st.webReq = &WebRequest{
 		HttpRequest:     r,
 		NamedParameters: params,
+		Query:           r.URL.Query(),
 	}
 
-	result := handler.Serve(webReq)
+	disp.dispatch(w, st.rec, st.webReq, m)
+}
+
+func (disp *RequestDispatcher) dispatch(w http.ResponseWriter, rec *statusRecorder, webReq *WebRequest, m *RequestMapping) {
+	r := webReq.HttpRequest
+
+	if m == nil {
+		if target := disp.trailingSlashRedirect(r.URL.EscapedPath(), r); target != "" {
+			code := http.StatusMovedPermanently
+			if r.Method != "GET" && r.Method != "HEAD" {
+				code = http.StatusPermanentRedirect
+			}
+			http.Redirect(w, r, target, code)
+			return
+		}
+
+		disp.serveUnmapped(w, rec, webReq)
+		return
+	}
+
+	result := m.chain.Serve(webReq)
+
+	if e, ok := result.(*errorResult); ok {
+		result = disp.errorHandler().Handle(e.err, webReq)
+	}
 
 	if err := disp.Mvc.HandleWebResult(result, w, r); err != nil {
-		panic(err)
+		disp.serveError(w, rec, webReq, err)
+	}
+}
+
+var gMetricsRegistryType reflect.Type = reflect.TypeOf((*wntr.MetricsRegistry)(nil))
+
+//Captures response status for request metrics and error handling
+//
+//  Hijacker and Pusher are forwarded, other optional interfaces
+//  are available via http.ResponseController
+type statusRecorder struct {
+	http.ResponseWriter
+	code    int
+	written bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.written = true
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.written = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.written = true
+		f.Flush()
 	}
+}
 
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking", r.ResponseWriter)
+	}
+	r.written = true
+	return h.Hijack()
 }
 
-func (disp *RequestDispatcher) serve404(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(404)
-	fmt.Fprint(w, "<html><body><h2>404 Not Found</h2><br>No Request Processor for URI:<br><br><h4>",
-		r.Method, " ",
-		"<u>", r.RequestURI, "</u></h4><br><br><i>Faithfully yours, WebMVC RequestDispatcher</i></body></html>")
+func (r *statusRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := r.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
 }
 
-func (disp *RequestDispatcher) findMappingForUri(uri string, method string) (*RequestMapping, map[string]string) {
-	for _, mapping := range disp.mappingTable {
-		if mapping.Method != "" && mapping.Method != method {
-			continue
+//Used by http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (disp *RequestDispatcher) errorHandler() *ErrorHandler {
+	if disp.Errors == nil {
+		return gDefaultErrorHandler
+	}
+	return disp.Errors
+}
+
+//Answers error, unless response is already started
+func (disp *RequestDispatcher) serveError(w http.ResponseWriter, rec *statusRecorder, r *WebRequest, err error) {
+	if rec.written {
+		log.Println("RequestDispatcher: Response is already started, dropping error:", err)
+		return
+	}
+
+	result := disp.errorHandler().Handle(err, r)
+
+	//Problem is rendered even if views are replaced or broken
+	if disp.Mvc != nil {
+		if err := disp.Mvc.HandleWebResult(result, w, r.HttpRequest); err == nil || rec.written {
+			return
 		}
+	}
+	RenderProblemView(result, w, r.HttpRequest)
+}
+
+//Answers 405 if path is mapped for other methods, 404 otherwise
+func (disp *RequestDispatcher) serveUnmapped(w http.ResponseWriter, rec *statusRecorder, r *WebRequest) {
+	if allowed := disp.allowedMethods(r.HttpRequest.URL.EscapedPath()); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		disp.serveError(w, rec, r, NewStatusError(http.StatusMethodNotAllowed, "Method %v is not allowed", r.HttpRequest.Method))
+		return
+	}
 
-		match := mapping.CompiledPattern.FindStringSubmatch(uri)
-		if len(match) == 0 {
+	disp.serveError(w, rec, r, NotFound("No route is mapped for the path"))
+}
+
+//Returns sorted methods having explicit mappings for uri
+func (disp *RequestDispatcher) allowedMethods(uri string) []string {
+	r := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, m := range disp.mappings {
+		if m.Method == "" || seen[m.Method] {
 			continue
 		}
+		seen[m.Method] = true
 
-		namedParams := make(map[string]string)
-
-		for i := 1; i < len(match); i++ {
-			pName := mapping.NamedParams[i-1]
-			namedParams[pName] = match[i]
+		if found, _ := disp.findMappingForUri(uri, m.Method); found != nil && found.Method == m.Method {
+			r = append(r, m.Method)
 		}
+	}
+
+	sort.Strings(r)
+	return r
+}
 
-		return mapping, namedParams
+//Returns target of trailing slash redirect, if enabled and mapped
+func (disp *RequestDispatcher) trailingSlashRedirect(uri string, r *http.Request) string {
+	if !disp.RedirectTrailingSlash || uri == "/" {
+		return ""
 	}
-	return nil, nil
+
+	//Cleaned path can't start with '//', which would redirect to another host
+	target := path.Clean(uri)
+	if !strings.HasSuffix(uri, "/") {
+		target += "/"
+	}
+
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+		return ""
+	}
+
+	if m, _ := disp.findMappingForUri(target, r.Method); m == nil {
+		return ""
+	}
+
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	return target
+}
+
+//Matches escaped uri path
+func (disp *RequestDispatcher) findMappingForUri(uri string, method string) (*RequestMapping, map[string]string) {
+	if disp.routes == nil {
+		return nil, nil
+	}
+
+	segs, err := decodePath(uri)
+	if err != nil {
+		return nil, nil
+	}
+
+	mapping, values := disp.routes.match(segs, method, nil, disp.CaseInsensitive)
+	if mapping == nil {
+		return nil, nil
+	}
+
+	namedParams := make(map[string]string)
+	for i, v := range values {
+		namedParams[mapping.NamedParams[i]] = v
+	}
+
+	return mapping, namedParams
 }
 
 func CompileRegex(p []string) (*regexp.Regexp, error) {
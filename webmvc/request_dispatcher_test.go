@@ -2,7 +2,11 @@ package webmvc
 
 import (
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -28,3 +32,227 @@ func scanAndCompile(p string) (*regexp.Regexp, error) {
 	a, _ := ScanMappingPattern(p)
 	return CompileRegex(a)
 }
+
+type namedController string
+
+func (c namedController) Serve(*WebRequest) WebResult {
+	return WebOk(string(c))
+}
+
+func TestRoutePrecedence(t *testing.T) {
+	disp := &RequestDispatcher{}
+
+	//Registered in reverse precedence order
+	for _, p := range []string{"/users/*", "/users/:id", "/users/me", "/files/*/raw/:name"} {
+		if err := disp.MapRequest(RequestMapping{Pattern: p, Handler: namedController(p)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		uri, pattern string
+		params       map[string]string
+	}{
+		{"/users/me", "/users/me", map[string]string{}},
+		{"/users/42", "/users/:id", map[string]string{"id": "42"}},
+		{"/users/42/posts", "/users/*", map[string]string{}},
+		{"/files/a/b/raw/c.txt", "/files/*/raw/:name", map[string]string{"name": "c.txt"}},
+	}
+
+	for _, c := range cases {
+		m, params := disp.findMappingForUri(c.uri, "GET")
+		if m == nil || m.Pattern != c.pattern || !reflect.DeepEqual(params, c.params) {
+			t.Fatal("Bad match of", c.uri, m, params)
+		}
+	}
+
+	if m, _ := disp.findMappingForUri("/orders", "GET"); m != nil {
+		t.Fatal("Unexpected match", m)
+	}
+}
+
+func TestConflictingRoutes(t *testing.T) {
+	disp := &RequestDispatcher{}
+
+	if err := disp.MapRequest(RequestMapping{Pattern: "/users/:id", Method: "GET"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := disp.MapRequest(RequestMapping{Pattern: "/users/:name", Method: "POST"}); err != nil {
+		t.Fatal("Different methods must not conflict", err)
+	}
+
+	if err := disp.MapRequest(RequestMapping{Pattern: "/users/:name", Method: "GET"}); err == nil {
+		t.Fatal("Conflict was not detected")
+	}
+
+	if err := disp.MapRequest(RequestMapping{Pattern: "/users/:id.json", Method: "GET"}); err != nil {
+		t.Fatal("Mixed segment was rejected", err)
+	}
+
+	if err := disp.MapRequest(RequestMapping{Pattern: "/users/:name.json", Method: "GET"}); err == nil {
+		t.Fatal("Conflict of mixed segments was not detected")
+	}
+
+	if err := disp.MapRequest(RequestMapping{Pattern: "/users/:.json"}); err == nil {
+		t.Fatal("Parameter without name was accepted")
+	}
+}
+
+func TestMixedSegments(t *testing.T) {
+	disp := &RequestDispatcher{}
+
+	for _, p := range []string{"/files/:id.json", "/files/index.json", "/files/:name", "/assets/*.js", "/v:major.:minor/ping"} {
+		if err := disp.MapRequest(RequestMapping{Pattern: p, Handler: namedController(p)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		uri, pattern string
+		params       map[string]string
+	}{
+		{"/files/42.json", "/files/:id.json", map[string]string{"id": "42"}},
+		{"/files/index.json", "/files/index.json", map[string]string{}},
+		{"/files/42.xml", "/files/:name", map[string]string{"name": "42.xml"}},
+		{"/assets/js/app.js", "/assets/*.js", map[string]string{}},
+		{"/v1.2/ping", "/v:major.:minor/ping", map[string]string{"major": "1", "minor": "2"}},
+	}
+
+	for _, c := range cases {
+		m, params := disp.findMappingForUri(c.uri, "GET")
+		if m == nil || m.Pattern != c.pattern || !reflect.DeepEqual(params, c.params) {
+			t.Fatal("Bad match of", c.uri, m, params)
+		}
+	}
+
+	for _, uri := range []string{"/assets/app.css", "/files/.json/x"} {
+		if m, _ := disp.findMappingForUri(uri, "GET"); m != nil {
+			t.Fatal("Unexpected match of", uri, m)
+		}
+	}
+}
+
+func TestOverlappingMixedSegments(t *testing.T) {
+	patterns := []string{"/files/:id.json", "/files/:name.:ext", "/files/:id.min.js", "/files/*.js"}
+
+	cases := map[string]string{
+		"/files/42.json":    "/files/:id.json",
+		"/files/42.xml":     "/files/:name.:ext",
+		"/files/app.min.js": "/files/:id.min.js",
+		"/files/lib/app.js": "/files/*.js",
+	}
+
+	//Precedence does not depend on registration order
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {1, 3, 0, 2}} {
+		disp := &RequestDispatcher{}
+		for _, i := range order {
+			if err := disp.MapRequest(RequestMapping{Pattern: patterns[i], Handler: namedController(patterns[i])}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for uri, pattern := range cases {
+			if m, _ := disp.findMappingForUri(uri, "GET"); m == nil || m.Pattern != pattern {
+				t.Fatal("Bad match of", uri, "in order", order, m)
+			}
+		}
+	}
+}
+
+func TestDecodedPathMatching(t *testing.T) {
+	disp := &RequestDispatcher{}
+	for _, p := range []string{"/files/:name", "/docs/read me"} {
+		if err := disp.MapRequest(RequestMapping{Pattern: p, Handler: namedController(p)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if m, params := disp.findMappingForUri("/files/a%2Fb.txt", "GET"); m == nil || params["name"] != "a/b.txt" {
+		t.Fatal("Encoded slash must stay in segment", m, params)
+	}
+
+	if m, _ := disp.findMappingForUri("/docs/read%20me", "GET"); m == nil {
+		t.Fatal("Encoded static segment was not matched")
+	}
+
+	if m, _ := disp.findMappingForUri("/files/%zz", "GET"); m != nil {
+		t.Fatal("Bad escaping was matched", m)
+	}
+}
+
+func TestCaseInsensitiveRoutes(t *testing.T) {
+	disp := &RequestDispatcher{}
+	if err := disp.MapRequest(RequestMapping{Pattern: "/Users/:id", Handler: namedController("users")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, _ := disp.findMappingForUri("/users/Bob", "GET"); m != nil {
+		t.Fatal("Routes are case sensitive by default")
+	}
+
+	disp = &RequestDispatcher{CaseInsensitive: true}
+	for _, p := range []string{"/Users/:id", "/Files/:id.JSON"} {
+		if err := disp.MapRequest(RequestMapping{Pattern: p, Handler: namedController(p)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if m, params := disp.findMappingForUri("/USERS/Bob", "GET"); m == nil || params["id"] != "Bob" {
+		t.Fatal("Case insensitive match failed", m, params)
+	}
+
+	if m, params := disp.findMappingForUri("/files/Doc.json", "GET"); m == nil || params["id"] != "Doc" {
+		t.Fatal("Case insensitive match of mixed segment failed", m, params)
+	}
+
+	if err := disp.MapRequest(RequestMapping{Pattern: "/users/:name"}); err == nil {
+		t.Fatal("Routes differing in case must conflict")
+	}
+}
+
+func TestTrailingSlashRedirect(t *testing.T) {
+	disp := &RequestDispatcher{RedirectTrailingSlash: true}
+	for _, p := range []string{"/users", "/posts/"} {
+		if err := disp.MapRequest(RequestMapping{Pattern: p, Handler: namedController(p)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		method, uri, location string
+		code                  int
+	}{
+		{"GET", "/users/?page=2", "/users?page=2", http.StatusMovedPermanently},
+		{"POST", "/posts", "/posts/", http.StatusPermanentRedirect},
+		{"GET", "/orders/", "", http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		disp.ServeHTTP(w, httptest.NewRequest(c.method, c.uri, nil))
+		if w.Code != c.code || w.Header().Get("Location") != c.location {
+			t.Fatal("Bad response to", c.method, c.uri, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	//Path must not become protocol-relative url
+	open := &RequestDispatcher{RedirectTrailingSlash: true}
+	if err := open.MapRequest(RequestMapping{Pattern: "/*/:name", Handler: namedController("any")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, uri := range []string{"//evil.com/", "/a//evil.com/"} {
+		w := httptest.NewRecorder()
+		open.ServeHTTP(w, httptest.NewRequest("GET", uri, nil))
+		if loc := w.Header().Get("Location"); strings.HasPrefix(loc, "//") {
+			t.Fatal("Redirect to another host", uri, loc)
+		}
+	}
+
+	disp.RedirectTrailingSlash = false
+	w := httptest.NewRecorder()
+	disp.ServeHTTP(w, httptest.NewRequest("GET", "/users/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatal("Redirect must be disabled", w.Code)
+	}
+}
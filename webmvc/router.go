@@ -0,0 +1,277 @@
+package webmvc
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//Segment trie of request mappings
+//
+//   Patterns are split into path segments. Segment is either static text,
+//   named parameter ':name' matching one non-empty segment, wildcard '*'
+//   matching one or more segments, or text mixed with them, like ':id.json'
+//   or '*.js'. Parameters of mixed segment match within the segment,
+//   wildcard matches the rest of path, e.g. '/assets/*.js' matches '/assets/js/app.js'.
+//   When several routes match a path, static segments beat mixed ones,
+//   mixed segments beat parameters and parameters beat wildcards,
+//   regardless of registration order. Of mixed segments, ones without wildcard go first,
+//   then ones with more text, then ones with fewer parameters, e.g. ':id.json' is tried
+//   before ':name.:ext'. Remaining ties are ordered by segment text.
+//   Mapping without method serves requests whose method has no explicit mapping.
+//   Routes inserted with fold match text of segments ignoring case,
+//   they have to be matched with fold too
+type routeNode struct {
+	static   map[string]*routeNode
+	mixed    []*mixedSegment
+	param    *routeNode
+	wildcard *routeNode
+	mappings map[string]*RequestMapping //by method
+}
+
+//Segment mixing text with parameters or wildcard
+type mixedSegment struct {
+	rx     *regexp.Regexp //Captures parameters
+	tail   bool           //Has wildcard, matches the rest of path
+	text   int            //Length of text around parameters
+	params int
+	node   *routeNode
+}
+
+func newRouteNode() *routeNode {
+	return &routeNode{
+		static:   make(map[string]*routeNode),
+		mappings: make(map[string]*RequestMapping),
+	}
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+//Splits escaped path and decodes every segment,
+//so encoded '/' does not split a segment
+func decodePath(escaped string) ([]string, error) {
+	segs := splitPath(escaped)
+	for i, seg := range segs {
+		v, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, err
+		}
+		segs[i] = v
+	}
+	return segs, nil
+}
+
+//Parses pattern into segments and parameter names
+func parseRoute(pattern string) ([]string, []string, error) {
+	segs := splitPath(pattern)
+	params := make([]string, 0)
+
+	for _, seg := range segs {
+		if seg == "*" || !strings.ContainsAny(seg, ":*") {
+			continue
+		}
+
+		_, names, _, err := compileSegment(seg, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Bad pattern '%v': %v", pattern, err)
+		}
+		params = append(params, names...)
+	}
+
+	return segs, params, nil
+}
+
+func isParamSegment(seg string) bool {
+	if !strings.HasPrefix(seg, ":") {
+		return false
+	}
+	name, tail := extractParam(seg[1:])
+	return name != "" && tail == ""
+}
+
+//Compiles mixed segment into expression capturing its parameters
+func compileSegment(seg string, fold bool) (*regexp.Regexp, []string, bool, error) {
+	expr := "^"
+	if fold {
+		expr = "(?i)^"
+	}
+	names := make([]string, 0)
+	tail := false
+
+	for rest := seg; rest != ""; {
+		pos := strings.IndexAny(rest, ":*")
+		if pos < 0 {
+			expr += regexp.QuoteMeta(rest)
+			break
+		}
+		expr += regexp.QuoteMeta(rest[:pos])
+
+		if rest[pos] == '*' {
+			if tail {
+				return nil, nil, false, fmt.Errorf("Several wildcards in segment '%v'", seg)
+			}
+			expr += ".*"
+			tail = true
+			rest = rest[pos+1:]
+			continue
+		}
+
+		name, r := extractParam(rest[pos+1:])
+		if name == "" {
+			return nil, nil, false, fmt.Errorf("Parameter without name in segment '%v'", seg)
+		}
+		expr += "([^/]+?)"
+		names = append(names, name)
+		rest = r
+	}
+
+	rx, err := regexp.Compile(expr + "$")
+	return rx, names, tail, err
+}
+
+//Adds mapping to trie, routes of same shape and method conflict
+func (n *routeNode) insert(segs []string, m *RequestMapping, fold bool) error {
+	for _, seg := range segs {
+		var next **routeNode
+		switch {
+		case seg == "*":
+			next = &n.wildcard
+		case isParamSegment(seg):
+			next = &n.param
+		case strings.ContainsAny(seg, ":*"):
+			n = n.mixedNode(seg, fold)
+			continue
+		default:
+			key := staticKey(seg, fold)
+			child, ok := n.static[key]
+			if !ok {
+				child = newRouteNode()
+				n.static[key] = child
+			}
+			n = child
+			continue
+		}
+
+		if *next == nil {
+			*next = newRouteNode()
+		}
+		n = *next
+	}
+
+	if old, ok := n.mappings[m.Method]; ok {
+		return fmt.Errorf("Pattern |%v| '%v' conflicts with already mapped '%v'", m.Method, m.Pattern, old.Pattern)
+	}
+	n.mappings[m.Method] = m
+	return nil
+}
+
+//Returns node of mixed segment, segments differing only in parameter names share it
+func (n *routeNode) mixedNode(seg string, fold bool) *routeNode {
+	rx, names, tail, _ := compileSegment(seg, fold) //Validated by parseRoute
+	for _, m := range n.mixed {
+		if m.rx.String() == rx.String() {
+			return m.node
+		}
+	}
+
+	text := len(seg) - len(names)
+	for _, name := range names {
+		text -= len(name)
+	}
+	if tail {
+		text--
+	}
+
+	m := &mixedSegment{rx, tail, text, len(names), newRouteNode()}
+	n.mixed = append(n.mixed, m)
+	sort.Slice(n.mixed, func(i, j int) bool {
+		return n.mixed[i].before(n.mixed[j])
+	})
+	return m.node
+}
+
+//Precedence of overlapping mixed segments, independent of registration order
+func (this *mixedSegment) before(other *mixedSegment) bool {
+	if this.tail != other.tail {
+		return !this.tail
+	}
+	if this.text != other.text {
+		return this.text > other.text
+	}
+	if this.params != other.params {
+		return this.params < other.params
+	}
+	return this.rx.String() < other.rx.String()
+}
+
+//Finds mapping for path segments and values of its parameters
+func (n *routeNode) match(segs []string, method string, values []string, fold bool) (*RequestMapping, []string) {
+	if len(segs) == 0 {
+		if m, ok := n.mappings[method]; ok {
+			return m, values
+		}
+		if m, ok := n.mappings[""]; ok {
+			return m, values
+		}
+		return nil, nil
+	}
+
+	if child, ok := n.static[staticKey(segs[0], fold)]; ok {
+		if m, v := child.match(segs[1:], method, values, fold); m != nil {
+			return m, v
+		}
+	}
+
+	for _, mixed := range n.mixed {
+		if m, v := mixed.match(segs, method, values, fold); m != nil {
+			return m, v
+		}
+	}
+
+	if n.param != nil && segs[0] != "" {
+		if m, v := n.param.match(segs[1:], method, append(values[:len(values):len(values)], segs[0]), fold); m != nil {
+			return m, v
+		}
+	}
+
+	//Wildcard is greedy
+	if n.wildcard != nil {
+		for k := len(segs); k >= 1; k-- {
+			if m, v := n.wildcard.match(segs[k:], method, values, fold); m != nil {
+				return m, v
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func staticKey(seg string, fold bool) string {
+	if fold {
+		return strings.ToLower(seg)
+	}
+	return seg
+}
+
+//Matches one segment, or the rest of path if segment has wildcard, greedily
+func (this *mixedSegment) match(segs []string, method string, values []string, fold bool) (*RequestMapping, []string) {
+	k := 1
+	if this.tail {
+		k = len(segs)
+	}
+
+	for ; k >= 1; k-- {
+		sub := this.rx.FindStringSubmatch(strings.Join(segs[:k], "/"))
+		if sub == nil {
+			continue
+		}
+		if m, v := this.node.match(segs[k:], method, append(values[:len(values):len(values)], sub[1:]...), fold); m != nil {
+			return m, v
+		}
+	}
+	return nil, nil
+}
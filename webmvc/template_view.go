@@ -0,0 +1,232 @@
+package webmvc
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/d-tar/wntr"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+//HTML template views module
+//
+//   Results with view name "users/list" are rendered by template file
+//   templates/users/list.html, see TemplateViews. Configured from
+//   "templates" section
+//
+//   var app struct {
+//           webmvc.EnableDefaultWebMvc
+//           Templates webmvc.EnableTemplateViews
+//           Funcs     FormatFuncs
+//   }
+//
+//   func listUsers(r *webmvc.WebRequest) webmvc.WebResult {
+//           return webmvc.WebPage("users/list", users)
+//   }
+type EnableTemplateViews struct {
+	Views TemplateViews `config:"templates"`
+}
+
+//Functions available in templates
+//
+//   Declare as components, functions of later components
+//   override earlier ones with the same name
+type TemplateFuncs interface {
+	TemplateFuncs() template.FuncMap
+}
+
+//Loads html/template views by view name
+//
+//   View name is path of template file relative to Dir, without extension.
+//   Every view is parsed together with Layout and Partials. If Layout is set,
+//   layout is executed and view provides its blocks, e.g.
+//
+//   layout.html:     <html><body>{{block "content" .}}{{end}}</body></html>
+//   users/list.html: {{define "content"}}{{range .}}{{template "user" .}}{{end}}{{end}}
+//   partials/user.html: {{define "user"}}<p>{{.Name}}</p>{{end}}
+//
+//   Parsed templates are cached unless Reload is set, use it in development
+//   to see changes of files without restart
+type TemplateViews struct {
+	//Templates directory, "templates" by default
+	Dir string
+	//Extension of template files, ".html" by default
+	Ext string
+	//Layout file relative to Dir, none by default
+	Layout string
+	//Glob of partial files relative to Dir, "partials/*.html" by default
+	Partials string
+	//Parse templates on every render
+	Reload bool
+
+	Funcs []TemplateFuncs `inject:"all"`
+
+	lock  sync.RWMutex
+	cache map[string]*template.Template
+}
+
+func _() {
+	var _ wntr.DependentModule = &EnableTemplateViews{}
+	var _ WebViewLoader = &TemplateViews{}
+}
+
+func (*EnableTemplateViews) ModuleName() string {
+	return "templates"
+}
+
+func (*EnableTemplateViews) ModuleDependencies() []string {
+	return []string{"webmvc"}
+}
+
+//Result rendered by view of given name, e.g. template
+func WebPage(view string, data interface{}) WebResult {
+	return &GenericWebResult{
+		data:     data,
+		httpCode: 200,
+		view:     view,
+	}
+}
+
+//Returns template view, nil if template file does not exist
+func (this *TemplateViews) LoadView(name string) (WebView, error) {
+	file, ok := this.viewFile(name)
+	if !ok {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	t, err := this.template(name, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var q WebViewFunc = func(mav WebResult, w http.ResponseWriter, r *http.Request) error {
+		return renderTemplate(t, mav, w)
+	}
+	return q, nil
+}
+
+/* Implementation */
+
+func (this *TemplateViews) dir() string {
+	if this.Dir == "" {
+		return "templates"
+	}
+	return this.Dir
+}
+
+//Returns template file of view, false for names escaping Dir
+func (this *TemplateViews) viewFile(name string) (string, bool) {
+	if name == "" || path.IsAbs(name) || strings.Contains(name, "\\") {
+		return "", false
+	}
+
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", false
+	}
+
+	ext := this.Ext
+	if ext == "" {
+		ext = ".html"
+	}
+	return filepath.Join(this.dir(), filepath.FromSlash(clean)+ext), true
+}
+
+func (this *TemplateViews) template(name, file string) (*template.Template, error) {
+	if !this.Reload {
+		this.lock.RLock()
+		t, ok := this.cache[name]
+		this.lock.RUnlock()
+		if ok {
+			return t, nil
+		}
+	}
+
+	t, err := this.parse(name, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !this.Reload {
+		this.lock.Lock()
+		if this.cache == nil {
+			this.cache = make(map[string]*template.Template)
+		}
+		this.cache[name] = t
+		this.lock.Unlock()
+	}
+	return t, nil
+}
+
+func (this *TemplateViews) parse(name, file string) (*template.Template, error) {
+	funcs := make(template.FuncMap)
+	for _, f := range this.Funcs {
+		for k, v := range f.TemplateFuncs() {
+			funcs[k] = v
+		}
+	}
+
+	t := template.New(name).Funcs(funcs)
+
+	partials := this.Partials
+	if partials == "" {
+		partials = "partials/*.html"
+	}
+
+	others, err := filepath.Glob(filepath.Join(this.dir(), partials))
+	if err != nil {
+		return nil, fmt.Errorf("Bad partials pattern '%v': %v", partials, err)
+	}
+
+	//First file is executed: layout if set, view otherwise
+	main := file
+	if this.Layout != "" {
+		main = filepath.Join(this.dir(), this.Layout)
+		others = append(others, file)
+	}
+	files := append([]string{main}, others...)
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl := t
+		if f != files[0] {
+			rel, _ := filepath.Rel(this.dir(), f)
+			tmpl = t.New(filepath.ToSlash(rel))
+		}
+		if _, err := tmpl.Parse(string(b)); err != nil {
+			return nil, fmt.Errorf("Failed to parse template %v: %v", f, err)
+		}
+	}
+
+	return t, nil
+}
+
+//Executes template into buffer first, so errors can still be answered
+func renderTemplate(t *template.Template, mav WebResult, w http.ResponseWriter) error {
+	var b bytes.Buffer
+	if err := t.Execute(&b, mav.Model()); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if mav.HttpCode() != 0 {
+		w.WriteHeader(mav.HttpCode())
+	}
+
+	_, err := b.WriteTo(w)
+	return err
+}
@@ -0,0 +1,96 @@
+package webmvc
+
+import (
+	"github.com/d-tar/wntr"
+	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type shoutFuncs struct{}
+
+func (shoutFuncs) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{"shout": strings.ToUpper}
+}
+
+type templateUser struct {
+	Name string
+}
+
+func TestTemplateViews(t *testing.T) {
+	var app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Templates  TemplateViews
+		Funcs      shoutFuncs
+		Users      HandlerFunc `@web-uri:"/users"`
+		Missing    HandlerFunc `@web-uri:"/missing"`
+	}
+
+	app.Templates.Dir = "testdata/templates"
+	app.Templates.Layout = "layout.html"
+	app.Users = func(*WebRequest) WebResult {
+		return WebPage("users/list", []templateUser{{"bob"}, {"<script>"}})
+	}
+	app.Missing = func(*WebRequest) WebResult {
+		return WebPage("../secret", nil)
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Stop()
+
+	w := httptest.NewRecorder()
+	app.Dispatcher.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+
+	expected := "<html><title>Users</title><body><ul><li>BOB</li><li>&lt;SCRIPT&gt;</li></ul></body></html>"
+	if w.Code != 200 || strings.TrimSpace(w.Body.String()) != expected || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatal("Bad page", w.Code, w.Header(), w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	app.Dispatcher.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != 500 {
+		t.Fatal("View outside of templates directory was rendered", w.Code, w.Body)
+	}
+}
+
+func TestTemplateReload(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "hello.html")
+	render := func(views *TemplateViews) string {
+		mvc := &WebViewResolver{Loaders: []WebViewLoader{views}}
+		if err := mvc.PreInit(); err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		if err := mvc.HandleWebResult(WebPage("hello", "bob"), w, httptest.NewRequest("GET", "/", nil)); err != nil {
+			t.Fatal(err)
+		}
+		return w.Body.String()
+	}
+
+	cached := &TemplateViews{Dir: dir}
+	dev := &TemplateViews{Dir: dir, Reload: true}
+
+	os.WriteFile(file, []byte("Hello {{.}}"), 0644)
+	if render(cached) != "Hello bob" || render(dev) != "Hello bob" {
+		t.Fatal("Bad initial render")
+	}
+
+	os.WriteFile(file, []byte("Bye {{.}}"), 0644)
+	if r := render(cached); r != "Hello bob" {
+		t.Fatal("Cached template was reparsed", r)
+	}
+	if r := render(dev); r != "Bye bob" {
+		t.Fatal("Template was not reloaded", r)
+	}
+}
@@ -0,0 +1,33 @@
+package webmvc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+/*
+Simple Plain Text View Implementation
+*/
+
+func NewTextView() WebView {
+	var q WebViewFunc = RenderTextView
+	return q
+}
+
+//Models implementing io.WriterTo write themselves, others are printed with fmt
+func RenderTextView(mav WebResult, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+
+	if mav.HttpCode() != 0 {
+		w.WriteHeader(mav.HttpCode())
+	}
+
+	if v, ok := mav.Model().(io.WriterTo); ok {
+		_, err := v.WriteTo(w)
+		return err
+	}
+
+	_, err := fmt.Fprint(w, mav.Model())
+	return err
+}
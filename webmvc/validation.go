@@ -0,0 +1,397 @@
+package webmvc
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//Custom validation rule
+//
+//   Declare it as component to use its rule in `validate` tags
+//
+//   type SlugValidator struct{}
+//
+//   func (SlugValidator) ValidationRule() string { return "slug" }
+//   func (SlugValidator) ValidateField(v reflect.Value, param string) error {...}
+//
+//   type CreatePost struct {
+//           Slug string `json:"slug" validate:"required,slug"`
+//   }
+type FieldValidator interface {
+	//Rule name used in tags
+	ValidationRule() string
+	//Checks field value, param is rule argument after '=', if any.
+	//Returned error message is reported to client
+	ValidateField(v reflect.Value, param string) error
+}
+
+//Validates handler inputs by `validate` tags
+//
+//   Built-in rules:
+//     required  - value is not zero, strings, slices and maps are not empty
+//     min=N     - number is at least N, length of string or collection is at least N
+//     max=N     - number is at most N, length of string or collection is at most N
+//     len=N     - length of string or collection is exactly N
+//     email     - string is an e-mail address
+//     oneof=a b - value is one of space separated options
+//
+//   Rules other than required are not checked on nil pointers.
+//   Nested structs are validated too, including elements of slices and maps,
+//   field path is built from json names, e.g. "items[0].name".
+//   Tags of AutoHandler arguments are checked when handler is initialized.
+//   Failures are answered by ErrorHandler as problems listing FieldErrors
+type RequestValidator struct {
+	Validators []FieldValidator `inject:"all"`
+}
+
+//Failed validation rule of a field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+//Validation failures of request
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return "Validation failed: " + strings.Join(msgs, ", ")
+}
+
+//Validates struct, returns FieldErrors if any rule failed.
+//Other errors report bad tags
+func (this *RequestValidator) Validate(v interface{}) error {
+	errs := make(FieldErrors, 0)
+	if err := this.validateStruct(reflect.Indirect(reflect.ValueOf(v)), "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//Checks that `validate` tags of type and its nested types use known rules
+//applicable to field types
+func (this *RequestValidator) CheckTags(ty reflect.Type) error {
+	return this.checkTypeTags(ty, make(map[reflect.Type]bool))
+}
+
+/* Implementation */
+
+var gDefaultValidator = &RequestValidator{}
+
+func (this *RequestValidator) validateStruct(v reflect.Value, path string, errs *FieldErrors) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	ty := v.Type()
+	for i := 0; i < ty.NumField(); i++ {
+		f := ty.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := fieldPath(path, f)
+		fv := v.Field(i)
+
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := this.validateField(fv, name, tag, errs); err != nil {
+				return fmt.Errorf("Field %v of %v: %v", f.Name, ty, err)
+			}
+		}
+
+		if err := this.validateNested(fv, name, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Validates structs within value, elements of collections are named by index or key
+func (this *RequestValidator) validateNested(v reflect.Value, path string, errs *FieldErrors) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return this.validateNested(v.Elem(), path, errs)
+		}
+
+	case reflect.Struct:
+		return this.validateStruct(v, path, errs)
+
+	case reflect.Slice, reflect.Array:
+		if !mayNest(v.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := this.validateNested(v.Index(i), fmt.Sprintf("%v[%v]", path, i), errs); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if !mayNest(v.Type().Elem()) {
+			return nil
+		}
+		//Sorted for stable order of errors
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			if err := this.validateNested(v.MapIndex(k), fmt.Sprintf("%v[%v]", path, k.Interface()), errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//Whether values of type may hold structs
+func mayNest(ty reflect.Type) bool {
+	for ty.Kind() == reflect.Ptr {
+		ty = ty.Elem()
+	}
+
+	switch ty.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func (this *RequestValidator) checkTypeTags(ty reflect.Type, visited map[reflect.Type]bool) error {
+	switch ty.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return this.checkTypeTags(ty.Elem(), visited)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	if visited[ty] {
+		return nil
+	}
+	visited[ty] = true
+
+	for i := 0; i < ty.NumField(); i++ {
+		f := ty.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			for _, rule := range strings.Split(tag, ",") {
+				rule, param := strings.TrimSpace(rule), ""
+				if i := strings.Index(rule, "="); i >= 0 {
+					rule, param = rule[:i], rule[i+1:]
+				}
+				if err := this.checkTag(f.Type, rule, param); err != nil {
+					return fmt.Errorf("Field %v of %v: %v", f.Name, ty, err)
+				}
+			}
+		}
+
+		if err := this.checkTypeTags(f.Type, visited); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Checks rule of tag is applicable to field type
+func (this *RequestValidator) checkTag(ty reflect.Type, rule, param string) error {
+	if rule == "required" {
+		return nil
+	}
+
+	for _, custom := range this.Validators {
+		if custom.ValidationRule() == rule {
+			return nil
+		}
+	}
+
+	if ty.Kind() == reflect.Ptr {
+		ty = ty.Elem()
+	}
+
+	switch rule {
+	case "min", "max", "len":
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			return fmt.Errorf("Rule %v requires number, got '%v'", rule, param)
+		}
+		switch ty.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if rule != "len" {
+				return nil
+			}
+		}
+		return fmt.Errorf("Rule %v is not applicable to %v", rule, ty)
+
+	case "email":
+		if ty.Kind() != reflect.String {
+			return fmt.Errorf("Rule email requires string, got %v", ty)
+		}
+		return nil
+
+	case "oneof":
+		return nil
+	}
+
+	return fmt.Errorf("Unknown validation rule '%v'", rule)
+}
+
+func fieldPath(path string, f reflect.StructField) string {
+	name := f.Name
+	if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+		name = tag
+	}
+
+	switch {
+	case f.Anonymous:
+		return path
+	case path == "":
+		return name
+	}
+	return path + "." + name
+}
+
+func (this *RequestValidator) validateField(v reflect.Value, name string, tag string, errs *FieldErrors) error {
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param := strings.TrimSpace(rule), ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			rule, param = rule[:i], rule[i+1:]
+		}
+
+		if rule == "required" {
+			if isEmptyValue(v) {
+				*errs = append(*errs, FieldError{name, rule, "must not be empty"})
+				return nil //Other rules make no sense for missing value
+			}
+			continue
+		}
+
+		fv := v
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		msg, err := this.checkRule(fv, rule, param)
+		if err != nil {
+			return err
+		}
+		if msg != "" {
+			*errs = append(*errs, FieldError{name, rule, msg})
+		}
+	}
+
+	return nil
+}
+
+//Returns message of failed rule
+func (this *RequestValidator) checkRule(v reflect.Value, rule, param string) (string, error) {
+	for _, custom := range this.Validators {
+		if custom.ValidationRule() == rule {
+			if err := custom.ValidateField(v, param); err != nil {
+				return err.Error(), nil
+			}
+			return "", nil
+		}
+	}
+
+	switch rule {
+	case "min", "max", "len":
+		return checkBound(v, rule, param)
+
+	case "email":
+		if v.Kind() != reflect.String {
+			return "", fmt.Errorf("Rule email requires string, got %v", v.Type())
+		}
+		if a, err := mail.ParseAddress(v.String()); err != nil || a.Address != v.String() {
+			return "must be a valid email address", nil
+		}
+		return "", nil
+
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if s == option {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of [%v]", param), nil
+	}
+
+	return "", fmt.Errorf("Unknown validation rule '%v'", rule)
+}
+
+func checkBound(v reflect.Value, rule, param string) (string, error) {
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return "", fmt.Errorf("Rule %v requires number, got '%v'", rule, param)
+	}
+
+	var n float64
+	unit := ""
+
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return "", fmt.Errorf("Rule %v is not applicable to %v", rule, v.Type())
+	}
+
+	switch {
+	case rule == "len" && unit == "":
+		return "", fmt.Errorf("Rule len is not applicable to %v", v.Type())
+	case rule == "len" && n != bound:
+		return fmt.Sprintf("must be exactly %v%v long", param, unit), nil
+	case rule == "min" && n < bound && unit != "":
+		return fmt.Sprintf("must be at least %v%v long", param, unit), nil
+	case rule == "min" && n < bound:
+		return fmt.Sprintf("must be at least %v", param), nil
+	case rule == "max" && n > bound && unit != "":
+		return fmt.Sprintf("must be at most %v%v long", param, unit), nil
+	case rule == "max" && n > bound:
+		return fmt.Sprintf("must be at most %v", param), nil
+	}
+
+	return "", nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,181 @@
+package webmvc
+
+import (
+	"errors"
+	"github.com/d-tar/wntr"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type signupAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type signupRequest struct {
+	Name    string         `json:"name" validate:"required,min=2,max=8"`
+	Email   string         `json:"email" validate:"required,email"`
+	Age     int            `json:"age" validate:"min=18"`
+	Plan    string         `json:"plan" validate:"oneof=free pro"`
+	Tags    []string       `json:"tags" validate:"max=2"`
+	Slug    string         `json:"slug" validate:"slug"`
+	Address *signupAddress `json:"address" validate:"required"`
+}
+
+type slugValidator struct{}
+
+func (slugValidator) ValidationRule() string {
+	return "slug"
+}
+
+func (slugValidator) ValidateField(v reflect.Value, param string) error {
+	if strings.ContainsAny(v.String(), " /") {
+		return errors.New("must not contain spaces or slashes")
+	}
+	return nil
+}
+
+func TestRequestValidation(t *testing.T) {
+	validator := &RequestValidator{Validators: []FieldValidator{slugValidator{}}}
+
+	valid := signupRequest{
+		Name:    "bob",
+		Email:   "bob@example.com",
+		Age:     20,
+		Plan:    "pro",
+		Slug:    "bob",
+		Address: &signupAddress{City: "Paris"},
+	}
+	if err := validator.Validate(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := signupRequest{
+		Name:    "b",
+		Email:   "bob at example",
+		Age:     17,
+		Plan:    "gold",
+		Tags:    []string{"a", "b", "c"},
+		Slug:    "bob smith",
+		Address: &signupAddress{},
+	}
+
+	err := validator.Validate(&invalid)
+	errs, ok := err.(FieldErrors)
+	if !ok {
+		t.Fatal("Expected field errors, got", err)
+	}
+
+	failed := make([]string, len(errs))
+	for i, e := range errs {
+		failed[i] = e.Field + ":" + e.Rule
+	}
+	expected := []string{"name:min", "email:email", "age:min", "plan:oneof", "tags:max", "slug:slug", "address.city:required"}
+	if !reflect.DeepEqual(failed, expected) {
+		t.Fatal("Bad field errors", errs)
+	}
+
+	if err := validator.Validate(signupRequest{Address: &signupAddress{"x"}}); len(err.(FieldErrors)) != 4 {
+		t.Fatal("Only required rules must fail on empty values", err)
+	}
+}
+
+func TestUnknownValidationRule(t *testing.T) {
+	var req struct {
+		Name string `validate:"slug"`
+	}
+
+	err := gDefaultValidator.Validate(req)
+	if _, ok := err.(FieldErrors); ok || err == nil {
+		t.Fatal("Unknown rule must be reported as error", err)
+	}
+}
+
+type pagedRequest struct {
+	Page int `@query-param:"page" validate:"min=1,max=100"`
+}
+
+func TestHandlerValidation(t *testing.T) {
+	h := newSmartHandler(t, func(req pagedRequest) WebResult {
+		return WebOk(req.Page)
+	})
+
+	if res := serveSmart(h, "/?page=5", nil); res.HttpCode() != 200 {
+		t.Fatal("Valid request was rejected", res.Model())
+	}
+
+	//Answered by ErrorHandler, as errors returned by handlers
+	res := serveSmart(h, "/?page=500", nil)
+	e, ok := res.(*errorResult)
+	if !ok || res.HttpCode() != 400 {
+		t.Fatal("Bad result of invalid request", res.HttpCode(), res.Model())
+	}
+
+	p := gDefaultErrorHandler.Handle(e.err, nil).Model().(*Problem)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "Page" || p.Errors[0].Rule != "max" {
+		t.Fatal("Bad field errors", p.Errors)
+	}
+}
+
+type orderRequest struct {
+	Items    []signupAddress           `json:"items" validate:"min=1"`
+	Shipping map[string]*signupAddress `json:"shipping"`
+}
+
+func TestCollectionValidation(t *testing.T) {
+	req := orderRequest{
+		Items:    []signupAddress{{"Paris"}, {}},
+		Shipping: map[string]*signupAddress{"home": {"Rome"}, "work": {}},
+	}
+
+	errs, ok := gDefaultValidator.Validate(req).(FieldErrors)
+	if !ok || len(errs) != 2 || errs[0].Field != "items[1].city" || errs[1].Field != "shipping[work].city" {
+		t.Fatal("Bad field errors of collection elements", errs)
+	}
+}
+
+type slugRequest struct {
+	Items []struct {
+		Name string `validate:"slug"`
+	}
+}
+
+func TestValidationTagsCheckedAtBoot(t *testing.T) {
+	type app struct {
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Validator  RequestValidator
+		Conv       wntr.GenericConversionService
+		Handler    SmartWebHandler `@web-uri:"/slug"`
+	}
+	handler := AutoHandler(func(req slugRequest) WebResult { return WebOk("ok") })
+
+	bad := app{Handler: handler}
+	ctx, err := wntr.FastBoot(&bad)
+	if err == nil {
+		ctx.Stop()
+		t.Fatal("Unknown rule of nested field was accepted")
+	}
+	if !strings.Contains(err.Error(), "Unknown validation rule 'slug'") {
+		t.Fatal("Unexpected boot error", err)
+	}
+
+	good := app{Handler: handler}
+	var rules struct {
+		Slug slugValidator
+	}
+	ctx, err = wntr.FastBoot(&good, &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Stop()
+
+	var badBound struct {
+		Name string `validate:"len=3"`
+		Age  int    `validate:"len=3"`
+	}
+	if err := gDefaultValidator.CheckTags(reflect.TypeOf(badBound)); err == nil || !strings.Contains(err.Error(), "Age") {
+		t.Fatal("Rule not applicable to field type was accepted", err)
+	}
+}
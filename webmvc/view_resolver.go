@@ -4,12 +4,30 @@ import (
 	"fmt"
 	"github.com/d-tar/wntr"
 	"net/http"
+	"sort"
 )
 
 var _ wntr.PreInitable = &WebViewResolver{}
-
+var _ wntr.PostInitable = &WebViewResolver{}
+
+//Finds WebView by name of WebResult
+//
+//   Results without view name are rendered by NegotiatingView,
+//   request bodies are decoded by Decoder of their Content-Type.
+//   Decoders and Encoders declared as components extend built-in ones,
+//   see DefaultDecoders and DefaultEncoders. Built-in form codecs convert
+//   fields by ConversionService, if one is declared.
+//   Views missing in view table are requested from WebViewLoader components
 type WebViewResolver struct {
+	Decoders    []BodyDecoder            `inject:"all"`
+	Encoders    []BodyEncoder            `inject:"all"`
+	Loaders     []WebViewLoader          `inject:"all"`
+	Conversions []wntr.ConversionService `inject:"all"`
+
 	viewTable map[string]WebView
+	//Codecs merged with built-in ones on PostInit
+	allDecoders []BodyDecoder
+	allEncoders []BodyEncoder
 }
 
 func (this *WebViewResolver) PreInit() error {
@@ -21,8 +39,24 @@ func (this *WebViewResolver) PreInit() error {
 
 	this.viewTable["JSON"] = NewJsonView()
 
-	this.viewTable[""] = this.viewTable["JSON"]
+	this.viewTable["TEXT"] = NewTextView()
+
+	this.viewTable["PROBLEM"] = NewProblemView()
+
+	this.viewTable[""] = this.NegotiatingView()
+
+	return nil
+}
+
+//Merges codecs once they are injected
+func (this *WebViewResolver) PostInit() error {
+	var conv wntr.ConversionService
+	if len(this.Conversions) > 0 {
+		conv = this.Conversions[0]
+	}
 
+	this.allDecoders = mergeDecoders(this.Decoders, defaultDecoders(conv))
+	this.allEncoders = mergeEncoders(this.Encoders, defaultEncoders(conv))
 	return nil
 }
 
@@ -30,15 +64,47 @@ func (this *WebViewResolver) SetWebViews(vws map[string]WebView) {
 	this.viewTable = vws
 }
 
+//Returns names of registered views, sorted
+func (this *WebViewResolver) ViewNames() []string {
+	r := make([]string, 0, len(this.viewTable))
+	for name := range this.viewTable {
+		r = append(r, name)
+	}
+	sort.Strings(r)
+	return r
+}
+
+//Loads views by name, e.g. from template files
+type WebViewLoader interface {
+	//Returns nil view if loader has no view of that name
+	LoadView(name string) (WebView, error)
+}
+
 func (h *WebViewResolver) HandleWebResult(r WebResult, w http.ResponseWriter, req *http.Request) error {
 	view, ok := h.viewTable[r.ViewName()]
 	if !ok {
-		return fmt.Errorf("No view named %v", r.ViewName())
+		var err error
+		if view, err = h.loadView(r.ViewName()); err != nil {
+			return err
+		}
 	}
 
 	return view.Render(r, w, req)
 }
 
+func (h *WebViewResolver) loadView(name string) (WebView, error) {
+	for _, l := range h.Loaders {
+		view, err := l.LoadView(name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load view %v: %v", name, err)
+		}
+		if view != nil {
+			return view, nil
+		}
+	}
+	return nil, fmt.Errorf("No view named %v", name)
+}
+
 /*
 ************************************************************************************
 Default Model Stage Implementation
@@ -48,6 +114,7 @@ Default Model Stage Implementation
 type GenericWebResult struct {
 	data     interface{}
 	httpCode int
+	view     string
 }
 
 func _() {
@@ -68,8 +135,17 @@ func WebErr(error interface{}) WebResult {
 	}
 }
 
-func (*GenericWebResult) ViewName() string {
-	return ""
+//Plain text result rendered by TEXT view
+func WebText(data interface{}) WebResult {
+	return &GenericWebResult{
+		data:     data,
+		httpCode: 200,
+		view:     "TEXT",
+	}
+}
+
+func (this *GenericWebResult) ViewName() string {
+	return this.view
 }
 
 func (this *GenericWebResult) Model() interface{} {
@@ -1,12 +1,19 @@
 package webmvc
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"github.com/d-tar/wntr"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"reflect"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -16,11 +23,53 @@ import (
 //	that were defined with @web-url  tag and registers them
 //	with http.Handler. Then it starts web server
 //
-//   On PreDestroy phase component destroys listener to stop
-// 	http.Server's serving cycle
+//   On shutdown component stops accepting connections and waits
+//	for in-flight requests to complete, see http.Server.Shutdown.
+//	Connections still active after ShutdownTimeout are closed.
+//	Declared by EnableDefaultWebMvc, server shuts down when the module stops,
+//	before any component is destroyed. Otherwise it shuts down on PreDestroy,
+//	so controllers have to be declared before it to serve drained requests
+//
+//   Server is a wntr.HealthIndicator: it is DOWN before it starts
+//	serving and while it drains, so readiness probes fail during shutdown
+//
+//   Server is configured by exported fields, which are bound from
+//   "server" configuration section when declared by EnableDefaultWebMvc
+//
+//   server:
+//     address: unix:/run/app.sock
+//     cert_file: /etc/app/tls.crt
+//     key_file: /etc/app/tls.key
+//     read_timeout: 10s
 type WebServerComponent struct {
+	//Listen address, ":8080" by default.
+	//  Address "unix:/path/to/socket" listens on Unix socket
+	Address string
+	//Listener to serve instead of listening on Address, e.g. in tests
+	Listener net.Listener `config:"-"`
+
+	//TLS is enabled when both files are set.
+	//  Changed certificate files are reloaded without restart
+	CertFile string
+	KeyFile  string
+	//HTTP/2 is negotiated over TLS unless disabled
+	DisableHTTP2 bool
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	//Time given to in-flight requests on shutdown, 30s by default
+	ShutdownTimeout time.Duration
+
 	listener  net.Listener
-	wait      *sync.Cond
+	server    *http.Server
+	lock      sync.Mutex
+	draining  bool
+	stopOnce  sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
 	exitError error
 
 	Dispatcher *RequestDispatcher `inject:"t"`
@@ -28,12 +77,20 @@ type WebServerComponent struct {
 
 func _() {
 	var _ wntr.PostInitable = &WebServerComponent{}
+	var _ wntr.PreDestroyable = &WebServerComponent{}
+	var _ wntr.HealthIndicator = &WebServerComponent{}
 }
 
 /*
 Begin Implementation
 */
 
+const defaultAddress = ":8080"
+const defaultShutdownTimeout = 30 * time.Second
+
+//Certificates are checked for changes at most once per interval
+var gCertCheckInterval = time.Second
+
 type requestMapping struct {
 	path    string
 	handler WebController
@@ -42,64 +99,230 @@ type requestMapping struct {
 var gWebControllerType reflect.Type = reflect.TypeOf((*WebController)(nil)).Elem()
 
 func (this *WebServerComponent) PostInit() error {
-	var m sync.Mutex
-	this.wait = sync.NewCond(&m)
-	this.wait.L.Lock()
+	this.done = make(chan struct{})
 
-	s := &http.Server{Addr: ":8080", Handler: this.Dispatcher}
+	s := &http.Server{
+		Handler:           this.Dispatcher,
+		ReadTimeout:       this.ReadTimeout,
+		ReadHeaderTimeout: this.ReadHeaderTimeout,
+		WriteTimeout:      this.WriteTimeout,
+		IdleTimeout:       this.IdleTimeout,
+		MaxHeaderBytes:    this.MaxHeaderBytes,
+	}
 
-	log.Println("Starting web server...")
+	if this.DisableHTTP2 {
+		s.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	useTLS := this.CertFile != "" || this.KeyFile != ""
+	if useTLS {
+		certs, err := newCertReloader(this.CertFile, this.KeyFile)
+		if err != nil {
+			return err
+		}
+		s.TLSConfig = &tls.Config{GetCertificate: certs.GetCertificate}
+	}
+
+	ln, err := this.listen()
+	if err != nil {
+		return err
+	}
+	this.listener = ln
+	this.server = s
+
+	log.Println("Starting web server on", ln.Addr())
 	go func() {
-		err := listenAndServe(s, this)
-		log.Println("WebRoutine done")
-		this.exitError = err
-		this.wait.Broadcast()
+		var err error
+		if useTLS {
+			err = s.ServeTLS(ln, "", "")
+		} else {
+			err = s.Serve(ln)
+		}
+
+		//Shutdown finishes the drain itself
+		if err != http.ErrServerClosed {
+			log.Println("WebRoutine failed:", err)
+			this.finish(err)
+		}
 	}()
 
 	return nil
 }
 
+//Blocks until server is stopped and in-flight requests are drained
 func (this *WebServerComponent) Wait() error {
-	this.wait.Wait()
+	<-this.done
 	return this.exitError
 }
 
 func (this *WebServerComponent) PreDestroy() {
-	log.Println("Closing WebSupport http listener")
-	this.listener.Close()
+	this.shutdown()
 }
 
-//Hack to capture listener object to perform
-//server shutdown on component stop
-func listenAndServe(srv *http.Server, web *WebServerComponent) error {
-	addr := srv.Addr
+//Stops accepting connections and drains in-flight requests, once
+func (this *WebServerComponent) shutdown() {
+	if this.server == nil {
+		return
+	}
+	this.stopOnce.Do(this.drain)
+}
+
+func (this *WebServerComponent) drain() {
+	this.lock.Lock()
+	this.draining = true
+	this.lock.Unlock()
+
+	timeout := this.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	log.Println("Shutting down web server, waiting up to", timeout, "for requests to complete")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var err error
+	if err = this.server.Shutdown(ctx); err != nil {
+		log.Println("Web server drain failed:", err, "- closing remaining connections")
+		this.server.Close()
+	}
+
+	this.finish(err)
+}
+
+func (this *WebServerComponent) HealthName() string {
+	return "web-server"
+}
+
+func (this *WebServerComponent) Health() wntr.Health {
+	this.lock.Lock()
+	draining := this.draining
+	this.lock.Unlock()
+
+	switch {
+	case this.done == nil:
+		return wntr.Down(errors.New("Server is not started"))
+	case draining:
+		return wntr.Down(errors.New("Server is shutting down"))
+	}
+
+	select {
+	case <-this.done:
+		return wntr.Down(fmt.Errorf("Server is stopped: %v", this.exitError))
+	default:
+		return wntr.Up("address", this.listener.Addr().String())
+	}
+}
+
+//Address of served listener
+func (this *WebServerComponent) Addr() net.Addr {
+	return this.listener.Addr()
+}
+
+func (this *WebServerComponent) listen() (net.Listener, error) {
+	if this.Listener != nil {
+		return this.Listener, nil
+	}
+
+	addr := this.Address
 	if addr == "" {
-		addr = ":http"
+		addr = defaultAddress
 	}
-	ln, err := net.Listen("tcp", addr)
-	if err != nil {
-		return err
+
+	if strings.HasPrefix(addr, "unix:") {
+		path := strings.TrimPrefix(addr, "unix:")
+		removeStaleSocket(path)
+		return net.Listen("unix", path)
 	}
-	web.listener = ln
-	return srv.Serve(tcpKeepAliveListener{ln.(*net.TCPListener)})
+
+	//TCP keep-alive is enabled by default
+	return net.Listen("tcp", addr)
 }
 
-//code below was coped from go's stdlib
+func (this *WebServerComponent) finish(err error) {
+	this.doneOnce.Do(func() {
+		this.exitError = err
+		close(this.done)
+	})
+}
 
-// tcpKeepAliveListener sets TCP keep-alive timeouts on accepted
-// connections. It's used by ListenAndServe and ListenAndServeTLS so
-// dead TCP connections (e.g. closing laptop mid-download) eventually
-// go away.
-type tcpKeepAliveListener struct {
-	*net.TCPListener
+//Socket file left by previous process prevents listening.
+//Socket is stale if nobody accepts connections on it
+func removeStaleSocket(path string) {
+	fi, err := os.Stat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		os.Remove(path)
+	}
 }
 
-func (ln tcpKeepAliveListener) Accept() (c net.Conn, err error) {
-	tc, err := ln.AcceptTCP()
+//Serves certificate, reloading it when files change
+type certReloader struct {
+	certFile, keyFile string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("Both CertFile and KeyFile are required for TLS")
+	}
+
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(r.lastModified()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) lastModified() time.Time {
+	var t time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		if fi, err := os.Stat(f); err == nil && fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t
+}
+
+func (r *certReloader) reload(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
 	if err != nil {
-		return
+		return fmt.Errorf("Failed to load certificate %v: %v", r.certFile, err)
 	}
-	tc.SetKeepAlive(true)
-	tc.SetKeepAlivePeriod(3 * time.Minute)
-	return tc, nil
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if time.Since(r.checked) >= gCertCheckInterval {
+		r.checked = time.Now()
+
+		if t := r.lastModified(); t.After(r.modTime) {
+			//Files may be replaced one by one, old certificate is served until both are valid
+			if err := r.reload(t); err != nil {
+				log.Println("Certificate reload failed:", err)
+			} else {
+				log.Println("Certificate reloaded", r.certFile)
+			}
+		}
+	}
+
+	return r.cert, nil
 }
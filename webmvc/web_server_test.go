@@ -0,0 +1,309 @@
+package webmvc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/d-tar/wntr"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func bootServer(t *testing.T, setup func(*WebServerComponent)) (*WebServerComponent, wntr.Context) {
+	var app struct {
+		Web        WebServerComponent
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Liveness   LivenessEndpoint `@web-uri:"/live"`
+		Health     wntr.HealthService
+	}
+	setup(&app.Web)
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &app.Web, ctx
+}
+
+func TestServeProvidedListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	web, ctx := bootServer(t, func(w *WebServerComponent) {
+		w.Listener = ln
+		w.ReadTimeout = time.Second
+	})
+	defer ctx.Stop()
+
+	resp, err := http.Get("http://" + web.Addr().String() + "/live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatal("Bad status", resp.StatusCode)
+	}
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "web.sock")
+
+	_, ctx := bootServer(t, func(w *WebServerComponent) {
+		w.Address = "unix:" + sock
+	})
+	defer ctx.Stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(string, string) (net.Conn, error) { return net.Dial("unix", sock) },
+	}}
+
+	resp, err := client.Get("http://unix/live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestUnixSocketInUseIsKept(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "web.sock")
+
+	//Stale socket file of crashed process is replaced
+	stale, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	_, ctx := bootServer(t, func(w *WebServerComponent) {
+		w.Address = "unix:" + sock
+	})
+
+	//Socket of running server is not
+	var app struct {
+		Web        WebServerComponent
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+	}
+	app.Web.Address = "unix:" + sock
+	if _, err := wntr.FastBoot(&app); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatal("Socket in use was replaced", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(string, string) (net.Conn, error) { return net.Dial("unix", sock) },
+	}}
+
+	resp, err := client.Get("http://unix/live")
+	if err != nil {
+		t.Fatal("Running server lost its socket", err)
+	}
+	resp.Body.Close()
+
+	ctx.Stop()
+}
+
+func TestTLSCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCertificate(t, certFile, keyFile, "first")
+
+	gCertCheckInterval = 0
+	defer func() { gCertCheckInterval = time.Second }()
+
+	web, ctx := bootServer(t, func(w *WebServerComponent) {
+		w.Address = "127.0.0.1:0"
+		w.CertFile, w.KeyFile = certFile, keyFile
+	})
+	defer ctx.Stop()
+
+	servedName := func() string {
+		conn, err := tls.Dial("tcp", web.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if conn.ConnectionState().NegotiatedProtocol != "h2" {
+			t.Fatal("HTTP/2 was not negotiated")
+		}
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	if name := servedName(); name != "first" {
+		t.Fatal("Bad certificate", name)
+	}
+
+	writeCertificate(t, certFile, keyFile, "second")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+
+	if name := servedName(); name != "second" {
+		t.Fatal("Certificate was not reloaded", name)
+	}
+}
+
+func writeCertificate(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	var app struct {
+		Web        WebServerComponent
+		Dispatcher RequestDispatcher
+		Mvc        WebViewResolver
+		Errors     ErrorHandler
+		Slow       HandlerFunc `@web-uri:"/slow"`
+		Health     wntr.HealthService
+	}
+	app.Web.Address = "127.0.0.1:0"
+
+	started := make(chan struct{})
+	var finished int32
+	app.Slow = func(*WebRequest) WebResult {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return WebOk("done")
+	}
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := app.Web.Health(); h.Status != wntr.HealthUp {
+		t.Fatal("Server is not ready", h)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + app.Web.Addr().String() + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		result <- err
+	}()
+
+	<-started
+	go ctx.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	if h := app.Health.Readiness(); h.Status != wntr.HealthDown {
+		t.Fatal("Draining server is ready", h)
+	}
+
+	if err := app.Web.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&finished) == 0 {
+		t.Fatal("Wait returned before request was drained")
+	}
+
+	if err := <-result; err != nil {
+		t.Fatal("In-flight request was cut off", err)
+	}
+}
+
+type slowController struct {
+	started   chan struct{}
+	destroyed int32
+}
+
+func (this *slowController) Serve(*WebRequest) WebResult {
+	close(this.started)
+	time.Sleep(200 * time.Millisecond)
+	if atomic.LoadInt32(&this.destroyed) != 0 {
+		return WebText("destroyed")
+	}
+	return WebText("ok")
+}
+
+func (this *slowController) PreDestroy() {
+	atomic.StoreInt32(&this.destroyed, 1)
+}
+
+func TestStopDrainsBeforeDestroyingControllers(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var app struct {
+		EnableDefaultWebMvc
+		Slow slowController `@web-uri:"/slow"`
+	}
+	app.Web.Listener = ln
+	app.Slow.started = make(chan struct{})
+
+	ctx, err := wntr.FastBoot(&app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result)
+	go func() {
+		resp, err := http.Get("http://" + app.Web.Addr().String() + "/slow")
+		if err != nil {
+			done <- result{"", err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		done <- result{string(b), err}
+	}()
+
+	<-app.Slow.started
+	ctx.Stop()
+
+	r := <-done
+	if r.err != nil || r.body != "ok" {
+		t.Fatal("In-flight request was not served by live controller", r.body, r.err)
+	}
+}
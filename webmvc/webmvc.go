@@ -1,6 +1,10 @@
 package webmvc
 
-import "net/http"
+import (
+	"github.com/d-tar/wntr"
+	"net/http"
+	"net/url"
+)
 
 //Model And View interface
 type WebResult interface {
@@ -10,8 +14,11 @@ type WebResult interface {
 }
 
 type WebRequest struct {
-	HttpRequest     *http.Request
+	HttpRequest *http.Request
+	//Decoded values of path parameters
 	NamedParameters map[string]string
+	//Parsed query string
+	Query url.Values
 }
 
 //Base web request processing interface
@@ -45,22 +52,43 @@ type HandlerFunc func(*WebRequest) WebResult
 func _() {
 	var h HandlerFunc = nil
 	var _ WebController = h
+	var _ wntr.Module = &EnableDefaultWebMvc{}
+	var _ wntr.ModuleStoppable = &EnableDefaultWebMvc{}
 }
 
 func (this HandlerFunc) Serve(r *WebRequest) WebResult {
 	return this(r)
 }
 
+//Default WebMVC module
+//
+//   Embed it into application configuration to serve web controllers
 type EnableDefaultWebMvc struct {
 	//Web Server Component
 	//  Serving HTTP commands and routes them to request dispatcher
-	Web WebServerComponent
+	//  Configured from "server" section, see wntr.EnableConfigFiles
+	Web WebServerComponent `config:"server"`
 	//Request Dispatcher
 	//   Finds request handler
 	//   Invokes handler with WebRequest and retrieves WebResult
 	//   Passes WebResult to View Resolver to render answer
-	Dispatcher RequestDispatcher
+	//  Configured from "dispatcher" section
+	Dispatcher RequestDispatcher `config:"dispatcher"`
+	//Validator of AutoHandler inputs
+	Validator RequestValidator
+	//Answers errors and panics of request processing
+	//  Declare ErrorMapper components to customize responses
+	Errors ErrorHandler
 	//View resovler component
 	//  Accepts WebResults and finds appropriate WebView to render it
 	Mvc WebViewResolver
 }
+
+func (*EnableDefaultWebMvc) ModuleName() string {
+	return "webmvc"
+}
+
+//Drains web server before components serving requests are destroyed
+func (this *EnableDefaultWebMvc) OnModuleStop() {
+	this.Web.shutdown()
+}
@@ -0,0 +1,147 @@
+//Helpers for testing wntr contexts
+//
+//   func TestController(t *testing.T) {
+//           var app MyApp
+//           ctx := wntrtest.Boot(t, &app)
+//
+//           var dao Dao
+//           wntrtest.Lookup(t, ctx, &dao)
+//   }
+//
+//  Booted contexts are stopped by t.Cleanup
+package wntrtest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/d-tar/wntr"
+	"reflect"
+	"testing"
+)
+
+//Creates context from definitions, starts it and registers Stop with t.Cleanup
+//
+//   Fails test with wiring diagnostics if context cannot be started
+func Boot(t testing.TB, definitions ...interface{}) wntr.Context {
+	t.Helper()
+	return BootWith(t, nil, definitions...)
+}
+
+//Same as Boot, but lets caller override components before start
+//
+//   ctx := wntrtest.BootWith(t, func(c wntr.OverridableContext) {
+//           c.OverrideByName("Dao", &MockDao{})
+//   }, &app)
+func BootWith(t testing.TB, setup func(wntr.OverridableContext), definitions ...interface{}) wntr.Context {
+	t.Helper()
+
+	ctx, err := wntr.CreateComplexContext(definitions...)
+	if err != nil {
+		t.Fatalf("Failed to create context: %v", err)
+	}
+
+	if setup != nil {
+		octx, ok := ctx.(wntr.OverridableContext)
+		if !ok {
+			t.Fatalf("Context %T does not support overrides", ctx)
+		}
+		setup(octx)
+	}
+
+	if err := ctx.Start(); err != nil {
+		t.Fatalf("Failed to start context: %v\n%v", err, Describe(ctx))
+	}
+
+	t.Cleanup(func() {
+		if err := ctx.Stop(); err != nil {
+			t.Errorf("Failed to stop context: %v", err)
+		}
+	})
+
+	return ctx
+}
+
+//Fills *vptr with single component of its type
+//
+//   Fails test if none or several components match
+func Lookup(t testing.TB, ctx wntr.Context, vptr interface{}) {
+	t.Helper()
+
+	comps := findComponents(t, ctx, vptr)
+
+	if len(comps) != 1 {
+		t.Fatalf("Expected single component of type %v, found %v\n%v",
+			reflect.TypeOf(vptr).Elem(), len(comps), Describe(ctx))
+		return
+	}
+
+	reflect.ValueOf(vptr).Elem().Set(reflect.ValueOf(comps[0].Instance()))
+}
+
+//Fails test if any component of *vptr type is registered
+func AssertMissing(t testing.TB, ctx wntr.Context, vptr interface{}) {
+	t.Helper()
+
+	if comps := findComponents(t, ctx, vptr); len(comps) != 0 {
+		t.Fatalf("Expected no components of type %v, found %v\n%v",
+			reflect.TypeOf(vptr).Elem(), len(comps), Describe(ctx))
+	}
+}
+
+//Fails test if any override requested on context was not applied
+func AssertOverridesApplied(t testing.TB, ctx wntr.Context) {
+	t.Helper()
+
+	octx, ok := ctx.(wntr.OverridableContext)
+	if !ok {
+		t.Fatalf("Context %T does not support overrides", ctx)
+		return
+	}
+
+	for _, o := range octx.Overrides() {
+		if !o.Applied() {
+			t.Errorf("Override %v was not applied", o)
+		}
+	}
+}
+
+//Returns human readable list of components registered in context
+func Describe(ctx wntr.Context) string {
+	mctx, ok := ctx.(*wntr.MutableContext)
+	if !ok {
+		return fmt.Sprintf("Context %T cannot be described", ctx)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "Registered components:")
+	for i, c := range mctx.Components() {
+		fmt.Fprintf(&b, "  %3d. %v", i, c.Type())
+		if name := wntr.ComponentName(c); name != "" {
+			fmt.Fprintf(&b, " name=%v", name)
+		}
+		if c.Tags() != "" {
+			fmt.Fprintf(&b, " tags=`%v`", c.Tags())
+		}
+		fmt.Fprintln(&b)
+	}
+
+	return b.String()
+}
+
+func findComponents(t testing.TB, ctx wntr.Context, vptr interface{}) []*wntr.ComponentImpl {
+	t.Helper()
+
+	ty := reflect.TypeOf(vptr)
+	if ty == nil || ty.Kind() != reflect.Ptr {
+		t.Fatalf("%T is not a pointer type", vptr)
+		return nil
+	}
+
+	mctx, ok := ctx.(*wntr.MutableContext)
+	if !ok {
+		t.Fatalf("Context %T does not support lookups", ctx)
+		return nil
+	}
+
+	return mctx.FindComponentsByType(ty.Elem())
+}
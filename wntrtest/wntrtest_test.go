@@ -0,0 +1,101 @@
+package wntrtest
+
+import (
+	"github.com/d-tar/wntr"
+	"strings"
+	"testing"
+)
+
+type Dao interface {
+	Find() string
+}
+
+type RealDao struct {
+}
+
+func (d *RealDao) Find() string {
+	return "real"
+}
+
+type MockDao struct {
+}
+
+func (d *MockDao) Find() string {
+	return "mock"
+}
+
+type Service struct {
+	Dao Dao `inject:"t"`
+}
+
+type App struct {
+	Dao     RealDao
+	Service Service
+}
+
+//Records failures instead of stopping test
+type failureRecorder struct {
+	*testing.T
+	messages []string
+}
+
+func (r *failureRecorder) Fatalf(format string, args ...interface{}) {
+	r.messages = append(r.messages, format)
+}
+
+func (r *failureRecorder) Errorf(format string, args ...interface{}) {
+	r.messages = append(r.messages, format)
+}
+
+func TestBootAndLookup(t *testing.T) {
+	var app App
+	ctx := Boot(t, &app)
+
+	var svc *Service
+	Lookup(t, ctx, &svc)
+
+	if svc != &app.Service || svc.Dao.Find() != "real" {
+		t.Fatal("Bad lookup result", svc)
+	}
+
+	var mock *MockDao
+	AssertMissing(t, ctx, &mock)
+}
+
+func TestBootWithOverrides(t *testing.T) {
+	var app App
+	ctx := BootWith(t, func(c wntr.OverridableContext) {
+		c.OverrideByName("Dao", &MockDao{})
+	}, &app)
+
+	AssertOverridesApplied(t, ctx)
+
+	if app.Service.Dao.Find() != "mock" {
+		t.Fatal("Override was not injected")
+	}
+}
+
+func TestLookupDiagnostics(t *testing.T) {
+	var app App
+	ctx := Boot(t, &app)
+
+	r := &failureRecorder{T: t}
+
+	var dao Dao
+	Lookup(r, ctx, &dao)
+
+	if len(r.messages) != 0 {
+		t.Fatal("Unexpected failure", r.messages)
+	}
+
+	var missing *MockDao
+	Lookup(r, ctx, &missing)
+
+	if len(r.messages) != 1 || !strings.Contains(r.messages[0], "Expected single component") {
+		t.Fatal("Lookup failure was not reported", r.messages)
+	}
+
+	if d := Describe(ctx); !strings.Contains(d, "name=Service") {
+		t.Fatal("Bad context description", d)
+	}
+}